@@ -1,12 +1,19 @@
 package rapi
 
 import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
 	"net/http"
 	"reflect"
+	"sort"
 )
 
 type Controller interface {
 	Init(w http.ResponseWriter, req *http.Request, root, prefix string, extras []string)
+	Context() context.Context
+	WithContext(ctx context.Context)
 	QueryParam(string) string
 	SetParam(string, interface{})
 	Param(string) interface{}
@@ -16,30 +23,339 @@ type Controller interface {
 	RenderJSONError(code int, s string)
 }
 
+// BeforeActioner is implemented by controllers processing request
+// before action. BeforeAction runs after middleware, returning false
+// stops processing.
+type BeforeActioner interface {
+	BeforeAction() bool
+}
+
+// AfterActioner is implemented by controllers processing request
+// after action. AfterAction runs after action even if action
+// returned error, unless processing was stopped before action.
+type AfterActioner interface {
+	AfterAction()
+}
+
+// Finalizer is implemented by controllers releasing resources
+// after request. Finalize runs after every request processed by
+// controller, even if action panics.
+type Finalizer interface {
+	Finalize()
+}
+
+// ActionErrorHandler is implemented by controllers rendering errors
+// returned by actions or arising from action arguments instead of
+// Router.ErrorHandler.
+type ActionErrorHandler interface {
+	HandleError(err error)
+}
+
 // ReqFunc is the function type for middlware
 type ReqFunc func(Controller) bool
 
 // JSONData shortcut for map[string]interface{}
 type JSONData map[string]interface{}
 
+// controller keeps information about registered controller
+type controller struct {
+	typ      reflect.Type
+	root     string
+	prefix   string
+	extras   []string
+	funcs    []ReqFunc
+	actions  map[string]*action
+	singular bool // resource without ID
+
+	factory func() Controller // creates controller serving request if set
+	proto   reflect.Value     // copy of registered controller
+	fields  []int             // exported fields copied from proto
+	inject  []injection       // services set to fields tagged inject
+}
+
+// injection is the service injected into controller field
+type injection struct {
+	field int
+	value reflect.Value
+}
+
+// action keeps dispatch information of controller action
+type action struct {
+	index int       // method index of controller pointer type
+	args  []argFunc // providers of action arguments
+	fails bool      // action returns error or value and error
+
+	before []int // method indexes of filters, see Filter
+	after  []int
+	around []int
+}
+
+func newController(i Controller, rootKey, prefix string, extras []string, funcs []ReqFunc) *controller {
+	v := reflect.Indirect(reflect.ValueOf(i))
+	ct := &controller{
+		typ:     v.Type(),
+		root:    rootKey,
+		prefix:  prefix,
+		extras:  extras,
+		funcs:   funcs,
+		actions: actionsOf(i, extras),
+		proto:   reflect.New(v.Type()).Elem(),
+	}
+	ct.proto.Set(v)
+	return ct
+}
+
+// newValue returns controller serving request created by factory
+// or copying exported fields set on registered controller.
+// Services are injected into fields tagged inject.
+func (ct *controller) newValue() reflect.Value {
+	var c reflect.Value
+	if ct.factory != nil {
+		c = reflect.ValueOf(ct.factory())
+		if c.Type() != reflect.PtrTo(ct.typ) {
+			panic(fmt.Sprintf("rapi: controller factory returned %s instead of *%s", c.Type(), ct.typ))
+		}
+	} else {
+		c = reflect.New(ct.typ)
+		for _, i := range ct.fields {
+			c.Elem().Field(i).Set(ct.proto.Field(i))
+		}
+	}
+	for _, v := range ct.inject {
+		c.Elem().Field(v.field).Set(v.value)
+	}
+	return c
+}
+
 // handle returns http handler function that will process controller actions
 func handle(i Controller, rootKey, prefix string, extras []string, funcs ...ReqFunc) http.HandlerFunc {
-	return func(w http.ResponseWriter, req *http.Request) {
-		t := reflect.Indirect(reflect.ValueOf(i)).Type()
-		c := reflect.New(t)
-		ctr := c.Interface().(Controller)
-		ctr.Init(w, req, rootKey, prefix, extras)
+	ct := newController(i, rootKey, prefix, extras, funcs)
+	ct.bind(nil)
+	return ct.ServeHTTP
+}
+
+func (ct *controller) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if rw, rreq := recovering(w, req); rw != nil {
+		defer recoverPanic(servingRouter(req), rw, rreq)
+		w, req = rw, rreq
+	}
+	if req.Method == "HEAD" {
+		w = headResponseWriter{w}
+	}
+	if ct.singular {
+		req = req.WithContext(context.WithValue(req.Context(), singularKey, true))
+	}
+
+	c := ct.newValue()
+	ctr := c.Interface().(Controller)
+	ctr.Init(w, req, ct.root, ct.prefix, ct.extras)
+	if f, ok := ctr.(Finalizer); ok {
+		defer f.Finalize()
+	}
+
+	act, ok := ct.actions[ctr.CurrentAction()]
+	if !ok {
+		u, _ := splitURL(ct.prefix, req.URL.Path, ct.singular)
+		allowed := ct.methods(u)
+		switch {
+		case req.Method == "OPTIONS":
+			w.Header().Set("Allow", allowHeader(allowed))
+			w.WriteHeader(http.StatusNoContent)
+		case len(allowed) > 0:
+			w.Header().Set("Allow", allowHeader(allowed))
+			methodNotAllowed(w, req)
+		default:
+			RenderJSONError(w, http.StatusBadRequest, "action not found")
+		}
+		return
+	}
 
+	for _, f := range ct.funcs {
+		if ok := f(ctr); !ok {
+			return
+		}
+	}
+	if b, ok := ctr.(BeforeActioner); ok && !b.BeforeAction() {
+		return
+	}
+	for _, i := range act.before {
+		if !c.Method(i).Call(nil)[0].Bool() {
+			return
+		}
+	}
+	req = requestOf(ctr, req)
+
+	if len(act.around) == 0 {
+		ct.invoke(w, req, ctr, c, act)
+	} else {
+		run := func() { ct.invoke(w, req, ctr, c, act) }
+		for j := len(act.around) - 1; j >= 0; j-- {
+			m, next := c.Method(act.around[j]), run
+			run = func() { m.Call([]reflect.Value{reflect.ValueOf(next)}) }
+		}
+		run()
+	}
+
+	for _, i := range act.after {
+		c.Method(i).Call(nil)
+	}
+	if a, ok := ctr.(AfterActioner); ok {
+		a.AfterAction()
+	}
+}
+
+// invoke calls action of controller value c with arguments
+// and renders results
+func (ct *controller) invoke(w http.ResponseWriter, req *http.Request, ctr Controller, c reflect.Value, act *action) {
+	var args []reflect.Value
+	if len(act.args) > 0 {
+		cl := &call{ct: ct, w: w, req: req, ctr: ctr}
+		args = make([]reflect.Value, len(act.args))
+		for i, f := range act.args {
+			v, err := f(cl)
+			if err != nil {
+				handleError(w, req, ctr, err)
+				return
+			}
+			args[i] = v
+		}
+	}
+
+	res := c.Method(act.index).Call(args)
+	if act.fails {
+		ct.render(w, req, ctr, res)
+	}
+}
+
+// render responds with results of action returning error or value and error.
+// Value is rendered under the root key with 201 status code for Create
+// action and 200 otherwise, nil value or no value gives 204 No Content.
+// Value without root key is rendered by embedded Request if any.
+func (ct *controller) render(w http.ResponseWriter, req *http.Request, ctr Controller, res []reflect.Value) {
+	if err, _ := res[len(res)-1].Interface().(error); err != nil {
+		handleError(w, req, ctr, err)
+		return
+	}
+
+	if len(res) == 1 || isNil(res[0]) {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	code := http.StatusOK
+	if ctr.CurrentAction() == "Create" {
+		code = http.StatusCreated
+	}
+	if ct.root != "" {
+		ctr.RenderJSON(code, JSONData{ct.root: res[0].Interface()})
+		return
+	}
+	if b, ok := ctr.(interface{ request() *Request }); ok {
+		b.request().renderJSON(code, res[0].Interface())
+		return
+	}
+	writeJSON(w, code, res[0].Interface())
+}
+
+// isNil returns true if v is nil pointer, map, slice or interface
+func isNil(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// requestOf returns HTTP request of controller embedding Request
+// with context updated by middleware or req otherwise
+func requestOf(ctr Controller, req *http.Request) *http.Request {
+	if b, ok := ctr.(interface{ request() *Request }); ok {
+		return b.request().req
+	}
+	return req
+}
+
+// methods returns HTTP methods having controller actions for the URL
+func (ct *controller) methods(u URL) []string {
+	res := []string{}
+	for _, m := range methodsOrder {
+		if _, ok := ct.actions[actionFor(m, u, ct.extras, ct.singular)]; ok {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// reqFuncsHandler returns handler running middleware before the handler.
+// Middleware receives base Request as controller, handler receives
+// request with context updated by middleware.
+func reqFuncsHandler(h http.Handler, funcs []ReqFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		ctr := &Request{}
+		ctr.Init(w, req, "", "", nil)
 		for _, f := range funcs {
 			if ok := f(ctr); !ok {
 				return
 			}
 		}
+		h.ServeHTTP(w, ctr.req)
+	})
+}
 
-		if method := c.MethodByName(ctr.CurrentAction()); method.IsValid() {
-			method.Call([]reflect.Value{})
-		} else {
-			RenderJSONError(w, http.StatusBadRequest, "action not found")
+// standard controller actions
+var stdActions = []string{"Index", "Show", "Create", "Update", "Destroy"}
+
+// actionsOf returns actions implemented by controller with method indexes,
+// so dispatch does not look methods up by name
+func actionsOf(i Controller, extras []string) map[string]*action {
+	res := make(map[string]*action)
+	t := reflect.TypeOf(i)
+	for _, v := range append(stdActions, extras...) {
+		if m, ok := t.MethodByName(v); ok {
+			res[v] = &action{index: m.Index}
 		}
 	}
+	return res
+}
+
+// actionNames returns sorted names of controller actions
+func (ct *controller) actionNames() []string {
+	res := make([]string, 0, len(ct.actions))
+	for k := range ct.actions {
+		res = append(res, k)
+	}
+	sort.Strings(res)
+	return res
+}
+
+// methodsOrder is the order of HTTP methods checked for controller actions
+var methodsOrder = []string{"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE"}
+
+// headResponseWriter discards response body written for HEAD request
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w headResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+// Flush sends response headers if underlying writer supports it
+func (w headResponseWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack takes over the connection if underlying writer supports it
+func (w headResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := w.ResponseWriter.(http.Hijacker); ok {
+		return h.Hijack()
+	}
+	return nil, nil, http.ErrNotSupported
+}
+
+// Unwrap returns underlying writer for http.ResponseController
+func (w headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
 }
@@ -0,0 +1,255 @@
+package rapi
+
+import (
+	"context"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+)
+
+var (
+	errorType           = reflect.TypeOf((*error)(nil)).Elem()
+	contextType         = reflect.TypeOf((*context.Context)(nil)).Elem()
+	requestType         = reflect.TypeOf((*http.Request)(nil))
+	writerType          = reflect.TypeOf((*http.ResponseWriter)(nil)).Elem()
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	baseType            = reflect.TypeOf(Request{})
+)
+
+// argFunc returns value of action argument for the call
+type argFunc func(c *call) (reflect.Value, error)
+
+// call keeps request processed by controller action
+type call struct {
+	ct  *controller
+	w   http.ResponseWriter
+	req *http.Request
+	ctr Controller
+}
+
+// id returns resource ID of the request
+func (c *call) id() string {
+	if b, ok := c.ctr.(interface{ request() *Request }); ok {
+		return b.request().URL.ID
+	}
+	u, _ := splitURL(c.ct.prefix, c.req.URL.Path, c.ct.singular)
+	return u.ID
+}
+
+// bind resolves arguments and filters of controller actions. Actions with
+// arguments which can not be provided are disabled and reported as errors.
+func (ct *controller) bind(svc *services) []error {
+	var errs []error
+	t := reflect.PtrTo(ct.typ)
+	for _, name := range ct.actionNames() {
+		a := ct.actions[name]
+		mt := t.Method(a.index).Type
+		args, err := ct.actionArgs(name, mt, svc)
+		if err == nil {
+			err = ct.actionResults(name, mt, a)
+		}
+		if err != nil {
+			errs = append(errs, err)
+			delete(ct.actions, name)
+			continue
+		}
+		a.args = args
+	}
+	errs = append(errs, ct.bindFields(svc)...)
+	return append(errs, ct.bindFilters(t)...)
+}
+
+// bindFields resolves services injected into controller fields tagged
+// inject and exported fields set on registered controller to be copied.
+// Embedded Request is never copied.
+func (ct *controller) bindFields(svc *services) []error {
+	var errs []error
+	for i := 0; i < ct.typ.NumField(); i++ {
+		f := ct.typ.Field(i)
+		if _, ok := f.Tag.Lookup("inject"); ok {
+			v, ok, err := svc.lookup(f.Type)
+			switch {
+			case f.PkgPath != "":
+				err = fmt.Errorf("rapi: field %s of controller *%s tagged inject is not exported", f.Name, ct.typ)
+			case err != nil:
+				err = fmt.Errorf("rapi: field %s of controller *%s: %v", f.Name, ct.typ, err)
+			case !ok:
+				err = fmt.Errorf("rapi: no service %s for field %s of controller *%s", f.Type, f.Name, ct.typ)
+			}
+			if err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			ct.inject = append(ct.inject, injection{field: i, value: v})
+			continue
+		}
+
+		if f.PkgPath != "" || f.Type == baseType || f.Type == reflect.PtrTo(baseType) || ct.proto.Field(i).IsZero() {
+			continue
+		}
+		ct.fields = append(ct.fields, i)
+	}
+	return errs
+}
+
+// actionArgs returns providers of arguments of the action method type.
+// Action may declare arguments of types:
+//  - context.Context, *http.Request and http.ResponseWriter
+//  - provided services, see Router.Provide
+//  - string, integer or encoding.TextUnmarshaler for the resource ID
+//  - pointer to struct decoded from JSON request body under root key
+func (ct *controller) actionArgs(name string, mt reflect.Type, svc *services) ([]argFunc, error) {
+	var args []argFunc
+	var id, body bool
+	for i := 1; i < mt.NumIn(); i++ {
+		t := mt.In(i)
+
+		switch t {
+		case contextType:
+			args = append(args, func(c *call) (reflect.Value, error) {
+				return reflect.ValueOf(c.req.Context()), nil
+			})
+			continue
+		case requestType:
+			args = append(args, func(c *call) (reflect.Value, error) {
+				return reflect.ValueOf(c.req), nil
+			})
+			continue
+		case writerType:
+			args = append(args, func(c *call) (reflect.Value, error) {
+				return reflect.ValueOf(&c.w).Elem(), nil
+			})
+			continue
+		}
+
+		v, ok, err := svc.lookup(t)
+		if err != nil {
+			return nil, fmt.Errorf("rapi: action %s of controller *%s: %v", name, ct.typ, err)
+		}
+		if ok {
+			args = append(args, func(c *call) (reflect.Value, error) {
+				return v, nil
+			})
+			continue
+		}
+
+		if f := idArg(t); f != nil {
+			if id || ct.singular || name == "Index" || name == "Create" {
+				return nil, fmt.Errorf("rapi: action %s of controller *%s has unexpected ID argument %s", name, ct.typ, t)
+			}
+			id = true
+			args = append(args, f)
+			continue
+		}
+
+		if t.Kind() == reflect.Ptr && t.Elem().Kind() == reflect.Struct {
+			if body {
+				return nil, fmt.Errorf("rapi: action %s of controller *%s has several body arguments", name, ct.typ)
+			}
+			body = true
+			args = append(args, bodyArg(t.Elem(), ct.root))
+			continue
+		}
+
+		return nil, fmt.Errorf("rapi: action %s of controller *%s has unsupported argument %s", name, ct.typ, t)
+	}
+	return args, nil
+}
+
+// actionResults checks results of the action method type.
+// Action may return nothing, error or value and error.
+func (ct *controller) actionResults(name string, mt reflect.Type, a *action) error {
+	switch {
+	case mt.NumOut() == 0:
+	case mt.NumOut() == 1 && mt.Out(0) == errorType:
+		a.fails = true
+	case mt.NumOut() == 2 && mt.Out(1) == errorType:
+		a.fails = true
+	default:
+		return fmt.Errorf("rapi: action %s of controller *%s must return error or value and error", name, ct.typ)
+	}
+	return nil
+}
+
+// idArg returns provider of the resource ID converted to type t
+// or nil if ID can not be converted to the type
+func idArg(t reflect.Type) argFunc {
+	switch {
+	case reflect.PtrTo(t).Implements(textUnmarshalerType) && t.Kind() != reflect.Ptr:
+		return func(c *call) (reflect.Value, error) {
+			v := reflect.New(t)
+			if err := v.Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(c.id())); err != nil {
+				return v, badRequest("invalid id")
+			}
+			return v.Elem(), nil
+		}
+	case t.Kind() == reflect.String:
+		return func(c *call) (reflect.Value, error) {
+			return reflect.ValueOf(c.id()).Convert(t), nil
+		}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Int64:
+		return func(c *call) (reflect.Value, error) {
+			n, err := strconv.ParseInt(c.id(), 10, t.Bits())
+			if err != nil {
+				return reflect.Value{}, badRequest("invalid id")
+			}
+			v := reflect.New(t).Elem()
+			v.SetInt(n)
+			return v, nil
+		}
+	case t.Kind() >= reflect.Uint && t.Kind() <= reflect.Uint64:
+		return func(c *call) (reflect.Value, error) {
+			n, err := strconv.ParseUint(c.id(), 10, t.Bits())
+			if err != nil {
+				return reflect.Value{}, badRequest("invalid id")
+			}
+			v := reflect.New(t).Elem()
+			v.SetUint(n)
+			return v, nil
+		}
+	}
+	return nil
+}
+
+// bodyArg returns provider of struct of type t decoded from JSON request body
+func bodyArg(t reflect.Type, root string) argFunc {
+	return func(c *call) (reflect.Value, error) {
+		v := reflect.New(t)
+		if err := decodeBody(c.req, root, v.Interface()); err != nil {
+			return v, badRequest("invalid request body")
+		}
+		return v, nil
+	}
+}
+
+// decodeBody decodes JSON request body into v. Value under the root key
+// is decoded if root is not empty. Empty body leaves v unchanged.
+func decodeBody(req *http.Request, root string, v interface{}) error {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil
+	}
+	defer req.Body.Close()
+
+	if root == "" {
+		if err := json.NewDecoder(req.Body).Decode(v); err != io.EOF {
+			return err
+		}
+		return nil
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.NewDecoder(req.Body).Decode(&body); err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	if raw, ok := body[root]; ok {
+		return json.Unmarshal(raw, v)
+	}
+	return nil
+}
@@ -0,0 +1,145 @@
+package rapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+type argsStore struct {
+	name string
+}
+
+type argsNamer interface {
+	Name() string
+}
+
+func (s *argsStore) Name() string {
+	return s.name
+}
+
+type argsCode string
+
+func (c *argsCode) UnmarshalText(b []byte) error {
+	if len(b) != 3 {
+		return fmt.Errorf("bad code")
+	}
+	*c = argsCode(strings.ToUpper(string(b)))
+	return nil
+}
+
+type argsPage struct {
+	Title string `json:"title"`
+}
+
+type ArgsC struct {
+	Request
+}
+
+func (c *ArgsC) Index(ctx context.Context, req *http.Request, s *argsStore) {
+	c.RenderJSON(200, JSONData{c.Root: s.name, "ctx": ctx == req.Context()})
+}
+
+func (c *ArgsC) Show(id int64, n argsNamer) {
+	c.RenderJSON(200, JSONData{c.Root: id, "store": n.Name()})
+}
+
+func (c *ArgsC) Update(id string, p *argsPage, w http.ResponseWriter) {
+	w.Header().Set("X-ID", id)
+	c.RenderJSON(200, JSONData{c.Root: p.Title})
+}
+
+func (c *ArgsC) GETCode(code argsCode) {
+	c.RenderJSON(200, JSONData{c.Root: code})
+}
+
+type BadArgsC struct {
+	Request
+}
+
+func (c *BadArgsC) Index(id int) {}
+
+func (c *BadArgsC) Show(f float64) {}
+
+func (c *BadArgsC) Update(a, b *argsPage) {}
+
+func (c *BadArgsC) Destroy() bool { return true }
+
+func TestActionArgs(t *testing.T) {
+	r := NewRouter()
+	r.Provide(&argsStore{name: "main"})
+	r.Route("/pages", &ArgsC{}, "page")
+	assertEqual(t, nil, r.Validate())
+
+	for _, v := range []struct {
+		method, path, body string
+		code               int
+		resp               string
+	}{
+		{"GET", "/pages", "", 200, `{"ctx":true,"page":"main"}`},
+		{"GET", "/pages/10", "", 200, `{"page":10,"store":"main"}`},
+		{"GET", "/pages/x", "", 400, `{"errors":{"message":["invalid id"]}}`},
+		{"PUT", "/pages/10", `{"page":{"title":"First"}}`, 200, `{"page":"First"}`},
+		{"PUT", "/pages/10", `{"page":`, 400, `{"errors":{"message":["invalid request body"]}}`},
+		{"GET", "/pages/abc/code", "", 200, `{"page":"ABC"}`},
+		{"GET", "/pages/ab/code", "", 400, `{"errors":{"message":["invalid id"]}}`},
+	} {
+		rec := newRecorder()
+		r.ServeHTTP(rec, newRequest(v.method, "http://localhost"+v.path, v.body))
+		assertEqual(t, v.code, rec.Code)
+		assertEqual(t, v.resp+"\n", rec.Body.String())
+	}
+
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("PUT", "http://localhost/pages/10", `{"page":{}}`))
+	assertEqual(t, "10", rec.Header().Get("X-ID"))
+}
+
+func TestActionArgsErrors(t *testing.T) {
+	r := NewRouter()
+	r.Route("/pages", &BadArgsC{}, "page")
+
+	err := r.Validate()
+	assertNotEqual(t, nil, err)
+	msg := err.Error()
+	for _, v := range []string{
+		"action Index of controller *rapi.BadArgsC has unexpected ID argument int",
+		"action Show of controller *rapi.BadArgsC has unsupported argument float64",
+		"action Update of controller *rapi.BadArgsC has several body arguments",
+		"action Destroy of controller *rapi.BadArgsC must return error or value and error",
+		"controller *rapi.BadArgsC at /pages has no actions",
+	} {
+		assertEqual(t, true, strings.Contains(msg, v))
+	}
+
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages/1", ""))
+	assertEqual(t, 400, rec.Code)
+
+	r = NewRouter()
+	r.Strict = true
+	defer func() {
+		assertNotEqual(t, nil, recover())
+	}()
+	r.Route("/pages", &ArgsC{}, "page")
+}
+
+func TestProvideShared(t *testing.T) {
+	r := NewRouter()
+	r.Provide(&argsStore{name: "main"})
+	r.Host("api.example.com").Route("/pages", &ArgsC{}, "page")
+	v := r.PathPrefix("/api").Versions("", "")
+	v.Version(1).Route("/pages", &ArgsC{}, "page")
+	assertEqual(t, nil, r.Validate())
+
+	req := newRequest("GET", "http://api.example.com/pages/1", "")
+	rec := newRecorder()
+	r.ServeHTTP(rec, req)
+	assertEqual(t, `{"page":1,"store":"main"}`+"\n", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/api/v1/pages/2", ""))
+	assertEqual(t, `{"page":2,"store":"main"}`+"\n", rec.Body.String())
+}
@@ -0,0 +1,309 @@
+package rapi
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// bindSources are struct tags read by Request.Bind except json
+var bindSources = []string{"path", "query", "header", "form"}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// FieldError describes the struct field failed to bind
+type FieldError struct {
+	Field  string // struct field name
+	Source string // path, query, header, form or json
+	Key    string // name of value in the source
+	Err    error
+}
+
+func (e *FieldError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("%s: %v", e.Source, e.Err)
+	}
+	return fmt.Sprintf("%s %q: %v", e.Source, e.Key, e.Err)
+}
+
+// BindError is returned by Request.Bind listing every failed field.
+// Actions returning BindError respond with 400 and failed fields in details.
+type BindError struct {
+	Fields []*FieldError
+}
+
+func (e *BindError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, v := range e.Fields {
+		msgs[i] = v.Error()
+	}
+	return "rapi: invalid request: " + strings.Join(msgs, "; ")
+}
+
+// httpError returns HTTPError with failed fields in details
+func (e *BindError) httpError() *HTTPError {
+	details := make(JSONData, len(e.Fields))
+	for _, v := range e.Fields {
+		k := v.Key
+		if k == "" {
+			k = v.Source
+		}
+		details[k] = v.Err.Error()
+	}
+	return &HTTPError{Code: http.StatusBadRequest, Message: "invalid request", Details: details}
+}
+
+// Bind fills struct pointed by dst from the request. Fields are filled
+// by tags from path parameters, query, headers and form values,
+// JSON request body under the root key is decoded into fields
+// tagged json only. Empty tag name means field name is used.
+// Values are converted to strings, bools, numbers, time.Duration,
+// encoding.TextUnmarshaler including time.Time, pointers and slices.
+// Fields missing in the request are left unchanged.
+// ex:
+//    var q struct {
+//        UserID int64     `path:"user_id"`
+//        Page   int       `query:"page"`
+//        Tags   []string  `query:"tag"`
+//        Since  time.Time `query:"since"`
+//        Token  string    `header:"X-API-Token"`
+//        Title  string    `json:"title"`
+//    }
+//    if err := p.Bind(&q); err != nil {
+//        return nil, err
+//    }
+func (r *Request) Bind(dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("rapi: Bind requires pointer to struct, got %T", dst)
+	}
+
+	var errs []*FieldError
+	if hasTag(v.Elem().Type(), "json") && isJSON(r.req) {
+		var body map[string]json.RawMessage
+		if err := decodeBody(r.req, r.Root, &body); err != nil {
+			errs = append(errs, &FieldError{Source: "json", Err: err})
+		} else {
+			errs = append(errs, bindJSON(v.Elem(), body)...)
+		}
+	}
+	if hasTag(v.Elem().Type(), "form") {
+		r.req.ParseMultipartForm(32 << 20)
+	}
+
+	errs = append(errs, r.bindFields(v.Elem())...)
+	if len(errs) > 0 {
+		return &BindError{Fields: errs}
+	}
+	return nil
+}
+
+// bindFields fills fields of struct value v from tagged sources
+func (r *Request) bindFields(v reflect.Value) []*FieldError {
+	var errs []*FieldError
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Type != baseType && f.Tag == "" {
+			errs = append(errs, r.bindFields(v.Field(i))...)
+			continue
+		}
+		if f.PkgPath != "" {
+			continue
+		}
+
+		for _, src := range bindSources {
+			key, ok := f.Tag.Lookup(src)
+			if !ok || key == "-" {
+				continue
+			}
+			if key == "" {
+				key = f.Name
+			}
+			vals := r.values(src, key)
+			if len(vals) == 0 {
+				continue
+			}
+			if err := setValue(v.Field(i), vals); err != nil {
+				errs = append(errs, &FieldError{Field: f.Name, Source: src, Key: key, Err: err})
+			}
+			break
+		}
+	}
+	return errs
+}
+
+// bindJSON decodes values of JSON object body into fields of struct value v
+// tagged json. Keys are matched like encoding/json does, other fields
+// are never filled from the body.
+func bindJSON(v reflect.Value, body map[string]json.RawMessage) []*FieldError {
+	var errs []*FieldError
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Type != baseType && f.Tag == "" {
+			errs = append(errs, bindJSON(v.Field(i), body)...)
+			continue
+		}
+		tag, ok := f.Tag.Lookup("json")
+		if !ok || f.PkgPath != "" {
+			continue
+		}
+		key, _, _ := strings.Cut(tag, ",")
+		if key == "-" {
+			continue
+		}
+		if key == "" {
+			key = f.Name
+		}
+
+		raw, ok := body[key]
+		if !ok {
+			for k, val := range body {
+				if strings.EqualFold(k, key) {
+					raw, ok = val, true
+					break
+				}
+			}
+		}
+		if !ok {
+			continue
+		}
+		if err := json.Unmarshal(raw, v.Field(i).Addr().Interface()); err != nil {
+			fe := &FieldError{Field: f.Name, Source: "json", Key: key, Err: err}
+			var te *json.UnmarshalTypeError
+			if errors.As(err, &te) {
+				fe.Err = fmt.Errorf("cannot use %s value as %s", te.Value, te.Type)
+			}
+			errs = append(errs, fe)
+		}
+	}
+	return errs
+}
+
+// values returns non empty request values of the source by key
+func (r *Request) values(src, key string) []string {
+	var vals []string
+	switch src {
+	case "path":
+		if v, ok := r.pathParams[key]; ok {
+			vals = []string{v}
+		} else if key == "id" {
+			vals = []string{r.URL.ID}
+		}
+	case "query":
+		vals = r.req.URL.Query()[key]
+	case "header":
+		vals = r.req.Header.Values(key)
+	case "form":
+		vals = r.req.PostForm[key]
+	}
+
+	res := vals[:0:0]
+	for _, v := range vals {
+		if v != "" {
+			res = append(res, v)
+		}
+	}
+	return res
+}
+
+// setValue converts values to type of v and sets it
+func setValue(v reflect.Value, vals []string) error {
+	if v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8 {
+		s := reflect.MakeSlice(v.Type(), len(vals), len(vals))
+		for i, val := range vals {
+			if err := setValue(s.Index(i), []string{val}); err != nil {
+				return err
+			}
+		}
+		v.Set(s)
+		return nil
+	}
+
+	s := vals[0]
+	if v.Kind() == reflect.Ptr {
+		p := reflect.New(v.Type().Elem())
+		if err := setValue(p.Elem(), vals); err != nil {
+			return err
+		}
+		v.Set(p)
+		return nil
+	}
+
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Slice:
+		v.SetBytes([]byte(s))
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("invalid boolean %q", s)
+		}
+		v.SetBool(b)
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid integer %q", s)
+		}
+		v.SetInt(n)
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid unsigned integer %q", s)
+		}
+		v.SetUint(n)
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		v.SetFloat(n)
+	default:
+		return fmt.Errorf("unsupported type %s", v.Type())
+	}
+	return nil
+}
+
+// hasTag returns true if struct type or its embedded structs
+// have field with the tag
+func hasTag(t reflect.Type, tag string) bool {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if _, ok := f.Tag.Lookup(tag); ok {
+			return true
+		}
+		if f.Anonymous && f.Type.Kind() == reflect.Struct && f.Type != baseType && hasTag(f.Type, tag) {
+			return true
+		}
+	}
+	return false
+}
+
+// isJSON returns true if request body may be JSON
+func isJSON(req *http.Request) bool {
+	ct := req.Header.Get("Content-Type")
+	if ct == "" {
+		return true
+	}
+	mt, _, _ := mime.ParseMediaType(ct)
+	return mt == "application/json" || strings.HasSuffix(mt, "+json")
+}
@@ -0,0 +1,130 @@
+package rapi
+
+import (
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+type bindPaging struct {
+	Page  int  `query:"page"`
+	Limit *int `query:"limit"`
+}
+
+type bindQuery struct {
+	bindPaging
+	UserID  int64         `path:"user_id"`
+	ID      string        `path:"id"`
+	Tags    []string      `query:"tag"`
+	Since   time.Time     `query:"since"`
+	Timeout time.Duration `query:"timeout"`
+	Active  bool          `query:""`
+	IP      net.IP        `header:"X-Real-IP"`
+	Token   string        `header:"X-API-Token"`
+	Title   string        `json:"title"`
+	Score   float64       `json:"score"`
+	Skip    string        `query:"-"`
+}
+
+type BindC struct {
+	Request
+}
+
+func (c *BindC) Show() (*bindQuery, error) {
+	var q bindQuery
+	if err := c.Bind(&q); err != nil {
+		return nil, err
+	}
+	return &q, nil
+}
+
+func (c *BindC) Update() (JSONData, error) {
+	var f struct {
+		Name  string `form:"name"`
+		Count []int  `form:"count"`
+	}
+	if err := c.Bind(&f); err != nil {
+		return nil, err
+	}
+	return JSONData{"name": f.Name, "count": f.Count}, nil
+}
+
+func TestBind(t *testing.T) {
+	req := newRequest("GET", "http://localhost/users/5/posts/7?page=2&limit=10&tag=a&tag=b&since=2026-01-02T03:04:05Z&timeout=1m&Active=true&Skip=x",
+		`{"post":{"title":"First","score":1.5}}`)
+	req.Header.Set("X-Real-IP", "10.0.0.1")
+	ctx := newReq(httpWriter, req, "post", "/users/:user_id/posts")
+
+	var q bindQuery
+	assertEqual(t, nil, ctx.Bind(&q))
+	assertEqual(t, 2, q.Page)
+	assertEqual(t, 10, *q.Limit)
+	assertEqual(t, int64(5), q.UserID)
+	assertEqual(t, "7", q.ID)
+	assertEqual(t, "a,b", strings.Join(q.Tags, ","))
+	assertEqual(t, time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC), q.Since)
+	assertEqual(t, time.Minute, q.Timeout)
+	assertEqual(t, true, q.Active)
+	assertEqual(t, "10.0.0.1", q.IP.String())
+	assertEqual(t, "token1", q.Token)
+	assertEqual(t, "First", q.Title)
+	assertEqual(t, 1.5, q.Score)
+	assertEqual(t, "", q.Skip)
+
+	assertNotEqual(t, nil, ctx.Bind(q))
+}
+
+func TestBindError(t *testing.T) {
+	req := newRequest("GET", "http://localhost/posts/1?page=x&limit=-&since=today&Active=maybe", `{"post":{"score":"high"}}`)
+	ctx := newReq(httpWriter, req, "post", "/posts")
+
+	var q bindQuery
+	err := ctx.Bind(&q)
+	be, ok := err.(*BindError)
+	assertEqual(t, true, ok)
+	fields := []string{}
+	for _, v := range be.Fields {
+		fields = append(fields, v.Source+":"+v.Field)
+	}
+	assertEqual(t, "json:Score query:Page query:Limit query:Since query:Active", strings.Join(fields, " "))
+	assertEqual(t, true, strings.Contains(err.Error(), `query "page": invalid integer "x"`))
+
+	r := NewRouter()
+	r.Route("/posts", &BindC{}, "post")
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/posts/1?page=x", ""))
+	assertEqual(t, http.StatusBadRequest, rec.Code)
+	assertEqual(t, `{"errors":{"details":{"page":"invalid integer \"x\""},"message":["invalid request"]}}`+"\n", rec.Body.String())
+}
+
+func TestBindForm(t *testing.T) {
+	r := NewRouter()
+	r.Route("/posts", &BindC{}, "post")
+
+	req := newRequest("PUT", "http://localhost/posts/1", "name=First&count=1&count=2")
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rec := newRecorder()
+	r.ServeHTTP(rec, req)
+	assertEqual(t, `{"post":{"count":[1,2],"name":"First"}}`+"\n", rec.Body.String())
+}
+
+func TestBindJSONTaggedOnly(t *testing.T) {
+	req := newRequest("POST", "http://localhost/users/5/posts?page=2",
+		`{"post":{"Title":"First","UserID":1,"Token":"spoofed","Page":9,"Admin":true,"Skip":"x"}}`)
+	ctx := newReq(httpWriter, req, "post", "/users/:user_id/posts")
+
+	var q struct {
+		bindQuery
+		Admin bool
+		Skip  string `json:"-"`
+	}
+	assertEqual(t, nil, ctx.Bind(&q))
+	assertEqual(t, "First", q.Title)
+	assertEqual(t, int64(5), q.UserID)
+	assertEqual(t, "token1", q.Token)
+	assertEqual(t, 2, q.Page)
+	assertEqual(t, false, q.Admin)
+	assertEqual(t, "", q.Skip)
+}
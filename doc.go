@@ -125,6 +125,12 @@
 //        }
 //    }
 //
+//    // Patch processed on PATCH /pages/1, optional.
+//    // Update is used for PATCH requests if controller has no Patch action.
+//    func (p *Pages) Patch() {
+//        // do some work here
+//    }
+//
 //    // Destroy processed on DELETE /pages/1
 //    func (p *Pages) Destroy() {
 //       page := findPage(p.URL.ID64())
@@ -140,21 +146,127 @@
 // prefix action name with HTTP method:
 //
 //    // POST /pages/clean or POST /pages/1/clean
-//    func (p *Pages) POSTClean {
+//    func (p *Pages) POSTClean() {
 //        // do some work here
 //    }
 //
 //    // DELETE /pages/clean or DELETE /pages/1/clean
-//    func (p *Pages) DELETEClean {
+//    func (p *Pages) DELETEClean() {
 //        // do some work here
 //    }
 //
 //    // GET /pages/stat or GET /pages/1/stat
-//    func (p *Pages) GETStat {
+//    func (p *Pages) GETStat() {
+//        // do some work here
+//    }
+//
+//    // PUT /pages/publish or PUT /pages/1/publish
+//    func (p *Pages) PUTPublish() {
 //        // do some work here
 //    }
 //
 //    ...
 //
+// HEAD requests are processed by GET actions with response body discarded.
+//
+// Actions may declare arguments provided by the router: context.Context,
+// *http.Request, http.ResponseWriter, services registered with Router.Provide,
+// resource ID of string, integer or encoding.TextUnmarshaler type and
+// pointer to struct decoded from JSON request body under the root key.
+// Actions with arguments which can not be provided are reported on registration:
+//
+//    r.Provide(db)
+//
+//    // PUT /pages/1 with {"page":{"name":"Page 1"}}
+//    func (p *Pages) Update(ctx context.Context, id int64, m *Page, db *sql.DB) {
+//        // do some work here
+//    }
+//
+// Controller serving request is created with exported fields set on the
+// registered controller copied and services set to fields tagged inject.
+// RouteFunc registers factory function creating controller instead:
+//
+//    type Pages struct {
+//        rapi.Request
+//        Cache *Cache            // copied from registered controller
+//        DB    *sql.DB `inject:""`
+//    }
+//
+//    r.Route("/pages", &Pages{Cache: cache}, "page")
+//    r.RouteFunc("/users", func() rapi.Controller { return newUsers(db) }, "user")
+//
+// Request.Bind fills struct from path parameters, query, headers, form values
+// and JSON body by field tags, returning BindError listing failed fields:
+//
+//    var q struct {
+//        Page int    `query:"page"`
+//        Name string `json:"name"`
+//    }
+//    if err := p.Bind(&q); err != nil {
+//        return nil, err // 400 with failed fields in details
+//    }
+//
+// Actions may return error or value and error instead of rendering response.
+// Value is rendered under the root key with 201 status for Create and 200
+// otherwise, nil value gives 204 No Content. HTTPError is rendered with
+// its code, other errors with 500 unless Router.ErrorHandler is set:
+//
+//    func (p *Pages) Show(id int64) (*Page, error) {
+//        page := findPage(id)
+//        if page == nil {
+//            return nil, &rapi.HTTPError{Code: 404, Message: "record not found"}
+//        }
+//        return page, nil
+//    }
+//
+// Route patterns may contain named parameters. Segment starting with ':'
+// captures corresponding URL path segment:
+//
+//    // GET /users/1/posts/2 processed by Posts.Show
+//    a.Route("/users/:user_id/posts", &Posts{}, "post")
+//
+//    func (p *Posts) Show() {
+//        userID := p.PathParam("user_id")
+//        // do some work here
+//    }
+//
+// Handlers registered with HandleFunc can read parameters with rapi.PathParam.
+//
+// Nested resources are registered with Resource. Parent IDs are available
+// with PathParam in nested controllers:
+//
+//    // /users and /users/:user_id/posts
+//    r.Resource("/users", &Users{}).Resource("/posts", &Posts{})
+//
+//    // GET, POST, PUT, DELETE /profile processed by Show, Create, Update, Destroy
+//    r.SingularResource("/profile", &Profile{})
+//
+// Middleware added with Route.Use is applied to every controller, handler
+// and file server registered beneath the route:
+//
+//    a := r.PathPrefix("/api/v1").Use(authenticate)
+//    a.Route("/pages", &Pages{}, "page")
+//    a.HandleFunc("/stats", statsHandler)
+//
+// Parameters set by middleware with SetParam are stored in the request
+// context under ParamKey, so handlers and net/http code beneath can read them.
+// Request.Context is canceled when client disconnects:
+//
+//    func authenticate(c rapi.Controller) bool {
+//        c.SetParam("user", findUser(c.Header("X-API-Token")))
+//        return true
+//    }
+//
+//    user := req.Context().Value(rapi.ParamKey("user")).(*User)
+//
+// Controllers may declare filters running before, after or around
+// their actions with Filters method, see Filter:
+//
+//    func (p *Pages) Filters() []rapi.Filter {
+//        return []rapi.Filter{{Before: "LoadPage", Only: []string{"Show", "Update"}}}
+//    }
+//
+// Controllers may customize request lifecycle implementing optional
+// BeforeActioner, AfterActioner, Finalizer and ActionErrorHandler interfaces.
 //
 package rapi
@@ -0,0 +1,69 @@
+package rapi
+
+import (
+	"errors"
+	"net/http"
+)
+
+// HTTPError is the error returned by controller actions
+// to respond with the status code and error details.
+// ex:
+//    func (p *Pages) Show(id int64) (*Page, error) {
+//        page := findPage(id)
+//        if page == nil {
+//            return nil, &rapi.HTTPError{Code: 404, Message: "record not found"}
+//        }
+//        return page, nil
+//    }
+type HTTPError struct {
+	Code    int
+	Message string
+	Details interface{}
+}
+
+func (e *HTTPError) Error() string {
+	return e.Message
+}
+
+// badRequest returns HTTPError with 400 status code
+func badRequest(msg string) error {
+	return &HTTPError{Code: http.StatusBadRequest, Message: msg}
+}
+
+// handleError passes the error to controller implementing
+// ActionErrorHandler or renders it otherwise
+func handleError(w http.ResponseWriter, req *http.Request, ctr Controller, err error) {
+	if h, ok := ctr.(ActionErrorHandler); ok {
+		h.HandleError(err)
+		return
+	}
+	renderError(w, req, err)
+}
+
+// renderError responds with the error returned by controller action
+// using Router.ErrorHandler of the routers serving request if set.
+// HTTPError with invalid status code is rendered with 500.
+func renderError(w http.ResponseWriter, req *http.Request, err error) {
+	if r := findRouter(req, func(r *Router) bool { return r.ErrorHandler != nil }); r != nil {
+		r.ErrorHandler(w, req, err)
+		return
+	}
+
+	var e *HTTPError
+	var be *BindError
+	if errors.As(err, &be) {
+		e = be.httpError()
+	} else if !errors.As(err, &e) {
+		RenderJSONError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+		return
+	}
+	code := e.Code
+	if code < 100 || code > 999 {
+		code = http.StatusInternalServerError
+	}
+	msg := JSONData{"message": []string{e.Message}}
+	if e.Details != nil {
+		msg["details"] = e.Details
+	}
+	RenderJSON(w, code, JSONData{"errors": msg})
+}
@@ -0,0 +1,132 @@
+package rapi
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+)
+
+type ResultC struct {
+	Request
+}
+
+func (c *ResultC) Index() ([]string, error) {
+	return []string{"a", "b"}, nil
+}
+
+func (c *ResultC) Show(id int64) (*argsPage, error) {
+	switch id {
+	case 404:
+		return nil, &HTTPError{Code: 404, Message: "record not found", Details: JSONData{"id": id}}
+	case 500:
+		return nil, fmt.Errorf("query: %w", errors.New("connection refused"))
+	case 418:
+		return nil, fmt.Errorf("wrapped: %w", &HTTPError{Code: 418, Message: "teapot"})
+	case 0:
+		return nil, nil
+	case 2:
+		return nil, &HTTPError{Message: "no code"}
+	case 3:
+		return nil, &HTTPError{Code: 1000, Message: "invalid code"}
+	}
+	return &argsPage{Title: "Page"}, nil
+}
+
+func (c *ResultC) Create(p *argsPage) (*argsPage, error) {
+	return p, nil
+}
+
+func (c *ResultC) Destroy() error {
+	return nil
+}
+
+func TestActionResults(t *testing.T) {
+	r := NewRouter()
+	r.Route("/pages", &ResultC{}, "page")
+	assertEqual(t, nil, r.Validate())
+
+	for _, v := range []struct {
+		method, path, body string
+		code               int
+		resp               string
+	}{
+		{"GET", "/pages", "", 200, `{"page":["a","b"]}` + "\n"},
+		{"GET", "/pages/1", "", 200, `{"page":{"title":"Page"}}` + "\n"},
+		{"GET", "/pages/0", "", 204, ""},
+		{"GET", "/pages/404", "", 404, `{"errors":{"details":{"id":404},"message":["record not found"]}}` + "\n"},
+		{"GET", "/pages/418", "", 418, `{"errors":{"message":["teapot"]}}` + "\n"},
+		{"GET", "/pages/500", "", 500, `{"errors":{"message":["Internal Server Error"]}}` + "\n"},
+		{"GET", "/pages/2", "", 500, `{"errors":{"message":["no code"]}}` + "\n"},
+		{"GET", "/pages/3", "", 500, `{"errors":{"message":["invalid code"]}}` + "\n"},
+		{"POST", "/pages", `{"page":{"title":"New"}}`, 201, `{"page":{"title":"New"}}` + "\n"},
+		{"DELETE", "/pages/1", "", 204, ""},
+	} {
+		rec := newRecorder()
+		r.ServeHTTP(rec, newRequest(v.method, "http://localhost"+v.path, v.body))
+		assertEqual(t, v.code, rec.Code)
+		assertEqual(t, v.resp, rec.Body.String())
+	}
+}
+
+func TestErrorHandler(t *testing.T) {
+	r := NewRouter()
+	r.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(err.Error()))
+	}
+	r.Route("/pages", &ResultC{}, "page")
+
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages/500", ""))
+	assertEqual(t, 503, rec.Code)
+	assertEqual(t, "query: connection refused", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages/x", ""))
+	assertEqual(t, 503, rec.Code)
+	assertEqual(t, "invalid id", rec.Body.String())
+}
+
+func TestErrorHandlerInherited(t *testing.T) {
+	r := NewRouter()
+	r.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(err.Error()))
+	}
+	r.Host("api.example.com").Route("/pages", &ResultC{}, "page")
+	r.PathPrefix("/api").Versions("acme", "").Version(1).Route("/pages", &ResultC{}, "page")
+	inner := NewRouter()
+	inner.Route("/pages", &ResultC{}, "page")
+	r.Mount("/legacy", inner)
+
+	for _, u := range []string{
+		"http://api.example.com/pages/500",
+		"http://localhost/api/v1/pages/500",
+		"http://localhost/legacy/pages/500",
+	} {
+		rec := newRecorder()
+		r.ServeHTTP(rec, newRequest("GET", u, ""))
+		assertEqual(t, 503, rec.Code)
+		assertEqual(t, "query: connection refused", rec.Body.String())
+	}
+}
+
+func TestActionResultsNoRoot(t *testing.T) {
+	handler := handle(&ResultC{}, "", "/pages", implements(&ResultC{}))
+	rec := newRecorder()
+	handler(rec, newRequest("GET", "http://localhost/pages/1", ""))
+	assertEqual(t, `{"title":"Page"}`+"\n", rec.Body.String())
+
+	req := newRequest("GET", "http://localhost/pages/1", "")
+	req.Header.Set("Accept-Encoding", "gzip")
+	rec = newRecorder()
+	handler(rec, req)
+	assertEqual(t, "gzip", rec.Header().Get("Content-Encoding"))
+	gz, err := gzip.NewReader(rec.Body)
+	assertEqual(t, nil, err)
+	body, _ := io.ReadAll(gz)
+	assertEqual(t, `{"title":"Page"}`+"\n", string(body))
+}
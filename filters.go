@@ -0,0 +1,100 @@
+package rapi
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Filter declares controller method running before, after or around
+// controller actions. Filter applies to all actions unless restricted
+// with Only or Except. Controllers declare filters with Filters method:
+//    func (p *Pages) Filters() []rapi.Filter {
+//        return []rapi.Filter{
+//            {Before: "LoadPage", Only: []string{"Show", "Update"}},
+//            {Around: "Measure"},
+//            {After: "Audit", Except: []string{"Index"}},
+//        }
+//    }
+//
+//    // LoadPage runs before action, returning false stops processing
+//    func (p *Pages) LoadPage() bool
+//
+//    // Measure wraps action, it must call action to process request
+//    func (p *Pages) Measure(action func())
+//
+//    // Audit runs after action even if action rendered error
+//    func (p *Pages) Audit()
+//
+// Before filters run after middleware passed to Route, around filters
+// run in declaration order with the first one outermost.
+type Filter struct {
+	Before string // method func() bool
+	After  string // method func()
+	Around string // method func(action func())
+
+	Only   []string
+	Except []string
+}
+
+var (
+	beforeType = reflect.TypeOf(func() bool { return true })
+	afterType  = reflect.TypeOf(func() {})
+	aroundType = reflect.TypeOf(func(func()) {})
+)
+
+// bindFilters resolves filters declared by controller to method indexes
+// of actions they apply to
+func (ct *controller) bindFilters(t reflect.Type) []error {
+	f, ok := reflect.New(ct.typ).Interface().(interface{ Filters() []Filter })
+	if !ok {
+		return nil
+	}
+
+	var errs []error
+	for _, v := range f.Filters() {
+		for _, a := range append(v.Only[:len(v.Only):len(v.Only)], v.Except...) {
+			if _, ok := ct.actions[a]; !ok {
+				errs = append(errs, fmt.Errorf("rapi: filter of controller *%s refers to unknown action %s", ct.typ, a))
+			}
+		}
+
+		for _, m := range []struct {
+			name string
+			typ  reflect.Type
+			list func(*action) *[]int
+		}{
+			{v.Before, beforeType, func(a *action) *[]int { return &a.before }},
+			{v.After, afterType, func(a *action) *[]int { return &a.after }},
+			{v.Around, aroundType, func(a *action) *[]int { return &a.around }},
+		} {
+			if m.name == "" {
+				continue
+			}
+			meth, ok := t.MethodByName(m.name)
+			if !ok || methodType(meth) != m.typ {
+				errs = append(errs, fmt.Errorf("rapi: filter %s of controller *%s must be method %s", m.name, ct.typ, m.typ))
+				continue
+			}
+			for name, a := range ct.actions {
+				if (len(v.Only) == 0 || containsString(v.Only, name)) && !containsString(v.Except, name) {
+					l := m.list(a)
+					*l = append(*l, meth.Index)
+				}
+			}
+		}
+	}
+	return errs
+}
+
+// methodType returns function type of method without receiver
+func methodType(m reflect.Method) reflect.Type {
+	in := make([]reflect.Type, m.Type.NumIn()-1)
+	for i := range in {
+		in[i] = m.Type.In(i + 1)
+	}
+	out := make([]reflect.Type, m.Type.NumOut())
+	for i := range out {
+		out[i] = m.Type.Out(i)
+	}
+	return reflect.FuncOf(in, out, m.Type.IsVariadic())
+}
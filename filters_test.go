@@ -0,0 +1,121 @@
+package rapi
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+var filterTrace []string
+
+type FilterC struct {
+	Request
+	page string
+}
+
+func (c *FilterC) Filters() []Filter {
+	return []Filter{
+		{Before: "Load", Except: []string{"Index"}},
+		{Around: "Outer", Only: []string{"Show", "Destroy"}},
+		{Around: "Inner", Only: []string{"Show"}},
+		{After: "Audit"},
+	}
+}
+
+func (c *FilterC) Load() bool {
+	filterTrace = append(filterTrace, "load")
+	if c.URL.ID == "0" {
+		c.RenderJSONError(404, "record not found")
+		return false
+	}
+	c.page = "page" + c.URL.ID
+	return true
+}
+
+func (c *FilterC) Outer(action func()) {
+	filterTrace = append(filterTrace, "outer")
+	action()
+	filterTrace = append(filterTrace, "/outer")
+}
+
+func (c *FilterC) Inner(action func()) {
+	filterTrace = append(filterTrace, "inner")
+	action()
+	filterTrace = append(filterTrace, "/inner")
+}
+
+func (c *FilterC) Audit() {
+	filterTrace = append(filterTrace, "audit")
+}
+
+func (c *FilterC) Index() {
+	filterTrace = append(filterTrace, "index")
+	c.RenderJSON(200, JSONData{c.Root: "all"})
+}
+
+func (c *FilterC) Show() {
+	filterTrace = append(filterTrace, "show")
+	c.RenderJSON(200, JSONData{c.Root: c.page})
+}
+
+func (c *FilterC) Destroy() error {
+	filterTrace = append(filterTrace, "destroy")
+	return errors.New("failed")
+}
+
+type BadFilterC struct {
+	Request
+}
+
+func (c *BadFilterC) Filters() []Filter {
+	return []Filter{
+		{Before: "Missing"},
+		{After: "Index"},
+		{Around: "Wrap", Only: []string{"Show"}},
+	}
+}
+
+func (c *BadFilterC) Index() {}
+
+func (c *BadFilterC) Wrap() {}
+
+func TestFilters(t *testing.T) {
+	r := NewRouter()
+	r.Route("/pages", &FilterC{}, "page")
+	assertEqual(t, nil, r.Validate())
+
+	for _, v := range []struct {
+		method, path string
+		code         int
+		resp         string
+		trace        string
+	}{
+		{"GET", "/pages", 200, `{"page":"all"}`, "index audit"},
+		{"GET", "/pages/1", 200, `{"page":"page1"}`, "load outer inner show /inner /outer audit"},
+		{"GET", "/pages/0", 404, `{"errors":{"message":["record not found"]}}`, "load"},
+		{"DELETE", "/pages/1", 500, `{"errors":{"message":["Internal Server Error"]}}`, "load outer destroy /outer audit"},
+	} {
+		filterTrace = nil
+		rec := newRecorder()
+		r.ServeHTTP(rec, newRequest(v.method, "http://localhost"+v.path, ""))
+		assertEqual(t, v.code, rec.Code)
+		assertEqual(t, v.resp+"\n", rec.Body.String())
+		assertEqual(t, v.trace, strings.Join(filterTrace, " "))
+	}
+}
+
+func TestFiltersErrors(t *testing.T) {
+	r := NewRouter()
+	r.Route("/pages", &BadFilterC{}, "page")
+
+	err := r.Validate()
+	assertNotEqual(t, nil, err)
+	msg := err.Error()
+	for _, v := range []string{
+		"filter Missing of controller *rapi.BadFilterC must be method func() bool",
+		"filter Wrap of controller *rapi.BadFilterC must be method func(func())",
+		"filter of controller *rapi.BadFilterC refers to unknown action Show",
+	} {
+		assertEqual(t, true, strings.Contains(msg, v))
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/vtg/rapi
+
+go 1.20
@@ -0,0 +1,95 @@
+package rapi
+
+import (
+	"net/http"
+	"strings"
+)
+
+// hostRouter is a sub-router serving requests for matching host
+type hostRouter struct {
+	scheme string
+	labels []string
+	router *Router
+}
+
+// Host returns sub-router serving requests for the host matching the pattern.
+// Pattern may start with scheme and may contain labels in curly braces
+// capturing corresponding part of the host name. Captured values are
+// available to controllers with Request.Param and to handlers with HostParam.
+// Requests not matching any host are served by the router itself.
+// Sub-router is created with CleanPath, TrailingSlash and Strict settings of the
+// router, so they must be set before calling Host. Sub-router falls back
+// to NotFound, MethodNotAllowed and ErrorHandler of the router.
+// ex:
+//    admin := r.Host("https://admin.example.com")
+//    admin.Route("/users", &Users{}, "user")
+//
+//    tenants := r.Host("{tenant}.example.com")
+//    tenants.Route("/pages", &Pages{}, "page")
+//
+//    func (p *Pages) Index() {
+//        tenant := p.Param("tenant").(string)
+//    }
+func (r *Router) Host(pattern string) *Router {
+	h := &hostRouter{router: r.subRouter()}
+	if i := strings.Index(pattern, "://"); i >= 0 {
+		h.scheme, pattern = strings.ToLower(pattern[:i]), pattern[i+3:]
+	}
+	h.labels = strings.Split(strings.ToLower(pattern), ".")
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t := r.load().clone()
+	t.hosts = append(t.hosts[:len(t.hosts):len(t.hosts)], h)
+	r.table.Store(t)
+	return h.router
+}
+
+// match checks request scheme and host returning captured host parameters
+func (h *hostRouter) match(req *http.Request) (map[string]string, bool) {
+	if h.scheme != "" && h.scheme != requestScheme(req) {
+		return nil, false
+	}
+
+	labels := strings.Split(strings.ToLower(stripPort(req.Host)), ".")
+	if len(labels) != len(h.labels) {
+		return nil, false
+	}
+
+	var params map[string]string
+	for i, v := range h.labels {
+		if len(v) > 2 && v[0] == '{' && v[len(v)-1] == '}' {
+			if labels[i] == "" {
+				return nil, false
+			}
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[v[1:len(v)-1]] = labels[i]
+			continue
+		}
+		if v != labels[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+func requestScheme(req *http.Request) string {
+	if req.URL.Scheme != "" {
+		return strings.ToLower(req.URL.Scheme)
+	}
+	if req.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// stripPort removes port from host
+func stripPort(host string) string {
+	i := strings.LastIndexByte(host, ':')
+	if i < 0 || strings.IndexByte(host[i:], ']') >= 0 {
+		return host
+	}
+	return host[:i]
+}
@@ -0,0 +1,91 @@
+package rapi
+
+import (
+	"net/http"
+	"testing"
+)
+
+type HostC struct {
+	Request
+}
+
+func (c *HostC) Index() {
+	c.RenderJSON(200, JSONData{"tenant": c.Param("tenant")})
+}
+
+func TestHost(t *testing.T) {
+	r := NewRouter()
+	r.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("public"))
+	})
+	r.Host("https://admin.example.com").HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("admin panel"))
+	})
+	tenants := r.Host("{tenant}.example.com")
+	tenants.Route("/pages", &HostC{}, "page")
+	tenants.HandleFunc("/", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(HostParam(req, "tenant")))
+	})
+
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "https://admin.example.com/", ""))
+	assertEqual(t, "admin panel", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://admin.example.com/", ""))
+	assertEqual(t, "admin", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://acme.example.com:8080/", ""))
+	assertEqual(t, "acme", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://acme.example.com/pages", ""))
+	assertEqual(t, "{\"tenant\":\"acme\"}\n", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://example.com/", ""))
+	assertEqual(t, "public", rec.Body.String())
+}
+
+func TestHostInheritsPolicies(t *testing.T) {
+	r := NewRouter()
+	r.TrailingSlash = Redirect301
+	r.NotFound = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte("custom not found"))
+	})
+	r.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		w.Write([]byte("custom not allowed"))
+	})
+	api := r.Host("api.example.com")
+	api.HandleFunc("/info/", HandlerForTest, "GET")
+	v := r.PathPrefix("/api").Versions("", "")
+	v.Version(1).HandleFunc("/info/", HandlerForTest)
+
+	for _, c := range []struct {
+		method, url string
+		code        int
+		body        string
+	}{
+		{"GET", "http://api.example.com/missing", 404, "custom not found"},
+		{"POST", "http://api.example.com/info/", 405, "custom not allowed"},
+		{"GET", "http://api.example.com/info", 301, ""},
+		{"GET", "http://localhost/api/v1/info", 301, ""},
+	} {
+		rec := newRecorder()
+		r.ServeHTTP(rec, newRequest(c.method, c.url, ""))
+		assertEqual(t, c.code, rec.Code)
+		if c.body != "" {
+			assertEqual(t, c.body, rec.Body.String())
+		}
+	}
+}
+
+func TestStripPort(t *testing.T) {
+	assertEqual(t, "example.com", stripPort("example.com:80"))
+	assertEqual(t, "example.com", stripPort("example.com"))
+	assertEqual(t, "[::1]", stripPort("[::1]:80"))
+	assertEqual(t, "[::1]", stripPort("[::1]"))
+}
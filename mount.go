@@ -0,0 +1,80 @@
+package rapi
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// mount keeps information about mounted handler serving request
+type mount struct {
+	prefix string // mount path of the handler
+	path   string // original request path
+}
+
+// Mount registers handler serving all requests beneath the prefix.
+// Prefix is stripped from request URL path before calling the handler.
+// Mount path and original path are available with MountPath and OriginalPath.
+// Router mounted this way generates URLs with Request.URLFor prefixed
+// with its mount path.
+// ex:
+//    legacy := rapi.NewRouter()
+//    legacy.Route("/pages", &Pages{}, "page")
+//    r.PathPrefix("/api").Mount("/legacy", legacy)
+//    // GET /api/legacy/pages/1 is served by legacy router as GET /pages/1
+func (r *Route) Mount(prefix string, h http.Handler) *Route {
+	return r.mountRoute(prefix, h).addRoute(false)
+}
+
+// mountRoute returns unregistered route mounting the handler
+func (r *Route) mountRoute(prefix string, h http.Handler) *Route {
+	rt := r.NewRoute(prefix)
+	rt.kind = KindMount
+	return rt.Handler(mountHandler(rt.prefix, h))
+}
+
+// mountHandler returns handler stripping the prefix pattern from
+// request URL path and recording mount information in request context
+func mountHandler(prefix string, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		_, rest, ok := matchPattern(prefix, req.URL.Path)
+		if !ok {
+			notFound(w, req)
+			return
+		}
+
+		m := mount{
+			prefix: strings.TrimSuffix(req.URL.Path[:len(req.URL.Path)-len(rest)], "/"),
+			path:   req.URL.Path,
+		}
+		if parent, ok := req.Context().Value(mountKey).(mount); ok {
+			m.prefix = parent.prefix + m.prefix
+			m.path = parent.path
+		}
+
+		if !strings.HasPrefix(rest, "/") {
+			rest = "/" + rest
+		}
+		url := *req.URL
+		url.Path, url.RawPath = rest, ""
+		req = req.WithContext(context.WithValue(req.Context(), mountKey, m))
+		req.URL = &url
+		h.ServeHTTP(w, req)
+	})
+}
+
+// MountPath returns path the handler serving request is mounted at
+// or empty string if handler is not mounted. See Route.Mount.
+func MountPath(req *http.Request) string {
+	m, _ := req.Context().Value(mountKey).(mount)
+	return m.prefix
+}
+
+// OriginalPath returns request URL path before mount prefixes were stripped.
+// See Route.Mount.
+func OriginalPath(req *http.Request) string {
+	if m, ok := req.Context().Value(mountKey).(mount); ok {
+		return m.path
+	}
+	return req.URL.Path
+}
@@ -0,0 +1,63 @@
+package rapi
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestMount(t *testing.T) {
+	inner := NewRouter()
+	inner.Route("/pages", &LinkC{}, "page")
+	inner.Route("/users/:user_id/posts", &TestC{}, "post").Name("posts")
+	inner.HandleFunc("/info", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.URL.Path + " " + MountPath(req) + " " + OriginalPath(req)))
+	})
+
+	middle := NewRouter()
+	middle.Mount("/v1/", inner)
+
+	r := NewRouter()
+	r.PathPrefix("/tenants/:tenant").Mount("/legacy", middle)
+	r.Mount("/std", http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.URL.Path))
+	}))
+
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/tenants/acme/legacy/v1/info", ""))
+	assertEqual(t, "/info /tenants/acme/legacy/v1 /tenants/acme/legacy/v1/info", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/tenants/acme/legacy/v1/pages", ""))
+	assertEqual(t, "/tenants/acme/legacy/v1/users/5/posts/7", rec.Header().Get("Location"))
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/std", ""))
+	assertEqual(t, "/", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/std/a/b", ""))
+	assertEqual(t, "/a/b", rec.Body.String())
+
+	assertEqual(t, KindMount, r.Routes()[0].Kind)
+}
+
+func TestMountRedirect(t *testing.T) {
+	inner := NewRouter()
+	inner.TrailingSlash = Redirect301
+	inner.CleanPath = Redirect308
+	inner.HandleFunc("/info/", HandlerForTest)
+
+	r := NewRouter()
+	r.CleanPath = RedirectOff
+	r.PathPrefix("/tenants/:tenant").Mount("/legacy", inner)
+
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/tenants/acme/legacy/info?a=1", ""))
+	assertEqual(t, http.StatusMovedPermanently, rec.Code)
+	assertEqual(t, "http://localhost/tenants/acme/legacy/info/?a=1", rec.Header().Get("Location"))
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/tenants/acme/legacy/a/../info/", ""))
+	assertEqual(t, http.StatusPermanentRedirect, rec.Code)
+	assertEqual(t, "http://localhost/tenants/acme/legacy/info/", rec.Header().Get("Location"))
+}
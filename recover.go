@@ -0,0 +1,123 @@
+package rapi
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"runtime/debug"
+)
+
+// recoverWriter tracks if response headers are written
+type recoverWriter struct {
+	http.ResponseWriter
+	written bool
+}
+
+func (w *recoverWriter) WriteHeader(code int) {
+	w.ResponseWriter.WriteHeader(code)
+	w.written = true
+}
+
+func (w *recoverWriter) Write(b []byte) (int, error) {
+	n, err := w.ResponseWriter.Write(b)
+	w.written = true
+	return n, err
+}
+
+// Flush sends buffered data to the client if underlying writer supports it
+func (w *recoverWriter) Flush() {
+	if f, ok := w.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+		w.written = true
+	}
+}
+
+// Hijack takes over the connection if underlying writer supports it
+func (w *recoverWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	h, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := h.Hijack()
+	if err == nil {
+		w.written = true
+	}
+	return conn, rw, err
+}
+
+// Push initiates HTTP/2 server push if underlying writer supports it
+func (w *recoverWriter) Push(target string, opts *http.PushOptions) error {
+	if p, ok := w.ResponseWriter.(http.Pusher); ok {
+		return p.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
+
+// ReadFrom copies response body from src using underlying writer
+// implementation if any, like sendfile of net/http server
+func (w *recoverWriter) ReadFrom(src io.Reader) (int64, error) {
+	if rf, ok := w.ResponseWriter.(io.ReaderFrom); ok {
+		n, err := rf.ReadFrom(src)
+		w.written = true
+		return n, err
+	}
+	return io.Copy(struct{ io.Writer }{w}, src)
+}
+
+// Unwrap returns underlying writer for http.ResponseController
+func (w *recoverWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// recovery keeps request dispatched by the innermost router
+// for looking up Router.OnPanic of routers serving request
+type recovery struct {
+	req *http.Request
+}
+
+// recovering returns writer and request for serving request
+// with panic recovery or nil writer if request is already recovered
+// by the outer router or controller
+func recovering(w http.ResponseWriter, req *http.Request) (*recoverWriter, *http.Request) {
+	if req.Context().Value(recoverKey) != nil {
+		return nil, req
+	}
+	return &recoverWriter{ResponseWriter: w}, req.WithContext(context.WithValue(req.Context(), recoverKey, &recovery{}))
+}
+
+// dispatching records request dispatched by router for panic recovery
+func dispatching(req *http.Request) {
+	if rc, ok := req.Context().Value(recoverKey).(*recovery); ok {
+		rc.req = req
+	}
+}
+
+// recoverPanic recovers panic of serving request, logs the stack,
+// calls Router.OnPanic hook of the innermost router having it
+// and renders JSON error if nothing is written
+func recoverPanic(r *Router, w *recoverWriter, req *http.Request) {
+	v := recover()
+	if v == nil {
+		return
+	}
+	if v == http.ErrAbortHandler {
+		panic(v)
+	}
+
+	stack := debug.Stack()
+	log.Printf("rapi: panic serving %s %s: %v\n%s", req.Method, req.URL.Path, v, stack)
+	if rc, ok := req.Context().Value(recoverKey).(*recovery); ok && rc.req != nil {
+		if h := findRouter(rc.req, func(r *Router) bool { return r.OnPanic != nil }); h != nil {
+			r = h
+		}
+	}
+	if r != nil && r.OnPanic != nil {
+		r.OnPanic(req, v, stack)
+	}
+	if !w.written {
+		RenderJSONError(w, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+}
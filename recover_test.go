@@ -0,0 +1,176 @@
+package rapi
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+type PanicC struct {
+	Request
+}
+
+func (c *PanicC) Index() {
+	panic("index failed")
+}
+
+func (c *PanicC) Show() {
+	c.RenderString(200, "partial")
+	panic("show failed")
+}
+
+func (c *PanicC) Create() {
+	var m JSONData
+	c.LoadJSONRequest(c.Root, &m)
+	c.RenderJSON(200, JSONData{c.Root: m})
+}
+
+func TestRecover(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	var reported []interface{}
+	r := NewRouter()
+	r.OnPanic = func(req *http.Request, v interface{}, stack []byte) {
+		reported = append(reported, v)
+		assertNotEqual(t, 0, len(stack))
+	}
+	r.Route("/pages", &PanicC{}, "page")
+	r.Route("/auth", &TestC{}, "page", func(c Controller) bool {
+		panic("auth failed")
+	})
+	r.Host("api.example.com").HandleFunc("/boom", func(w http.ResponseWriter, req *http.Request) {
+		panic("handler failed")
+	})
+
+	for _, v := range []struct {
+		url  string
+		code int
+		resp string
+	}{
+		{"http://localhost/pages", 500, `{"errors":{"message":["Internal Server Error"]}}` + "\n"},
+		{"http://localhost/pages/1", 200, "partial"},
+		{"http://localhost/auth", 500, `{"errors":{"message":["Internal Server Error"]}}` + "\n"},
+		{"http://api.example.com/boom", 500, `{"errors":{"message":["Internal Server Error"]}}` + "\n"},
+	} {
+		rec := newRecorder()
+		r.ServeHTTP(rec, newRequest("GET", v.url, ""))
+		assertEqual(t, v.code, rec.Code)
+		assertEqual(t, v.resp, rec.Body.String())
+	}
+	assertEqual(t, 4, len(reported))
+	assertEqual(t, "index failed", reported[0])
+	assertEqual(t, "handler failed", reported[3])
+
+	handler := handle(&PanicC{}, "page", "/pages", nil)
+	rec := newRecorder()
+	handler(rec, newRequest("GET", "http://localhost/pages", ""))
+	assertEqual(t, 500, rec.Code)
+}
+
+func TestRecoverSubRouterHook(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	var reported []string
+	hook := func(name string) func(*http.Request, interface{}, []byte) {
+		return func(req *http.Request, v interface{}, stack []byte) {
+			reported = append(reported, name+":"+v.(string))
+		}
+	}
+	panicking := func(w http.ResponseWriter, req *http.Request) {
+		panic(req.URL.Path)
+	}
+
+	r := NewRouter()
+	r.OnPanic = hook("root")
+	h := r.Host("api.example.com")
+	h.OnPanic = hook("host")
+	h.HandleFunc("/p", panicking)
+	v := r.PathPrefix("/api").Versions("acme", "")
+	v.Version(1).HandleFunc("/p", panicking)
+	inner := NewRouter()
+	inner.OnPanic = hook("mount")
+	inner.HandleFunc("/p", panicking)
+	r.Mount("/legacy", inner)
+
+	for _, u := range []string{"http://api.example.com/p", "http://localhost/api/v1/p", "http://localhost/legacy/p"} {
+		rec := newRecorder()
+		r.ServeHTTP(rec, newRequest("GET", u, ""))
+		assertEqual(t, 500, rec.Code)
+	}
+	assertEqual(t, "host:/p root:/p mount:/p", strings.Join(reported, " "))
+}
+
+type HijackC struct {
+	Request
+}
+
+func (c *HijackC) Index(w http.ResponseWriter) {
+	if _, _, err := http.NewResponseController(w).Hijack(); err != nil {
+		c.RenderString(200, err.Error())
+		return
+	}
+	panic("hijacked")
+}
+
+// hijackRecorder is the recorder supporting connection hijacking
+type hijackRecorder struct {
+	*httptest.ResponseRecorder
+	hijacked bool
+}
+
+func (w *hijackRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	w.hijacked = true
+	return nil, nil, nil
+}
+
+func TestRecoverHijack(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	r := NewRouter()
+	r.Route("/pages", &HijackC{}, "page")
+	r.HandleFunc("/ws", func(w http.ResponseWriter, req *http.Request) {
+		if _, _, err := w.(http.Hijacker).Hijack(); err != nil {
+			w.Write([]byte(err.Error()))
+			return
+		}
+		panic("hijacked")
+	})
+
+	for _, v := range []struct{ method, path string }{{"GET", "/ws"}, {"GET", "/pages"}, {"HEAD", "/pages"}} {
+		rec := &hijackRecorder{ResponseRecorder: newRecorder()}
+		r.ServeHTTP(rec, newRequest(v.method, "http://localhost"+v.path, ""))
+		assertEqual(t, true, rec.hijacked)
+		assertEqual(t, "", rec.Body.String())
+
+		rec = &hijackRecorder{ResponseRecorder: newRecorder()}
+		r.ServeHTTP(rec.ResponseRecorder, newRequest(v.method, "http://localhost"+v.path, ""))
+		assertEqual(t, false, rec.hijacked)
+		assertEqual(t, 200, rec.Code)
+	}
+
+	w := &recoverWriter{ResponseWriter: newRecorder()}
+	assertEqual(t, true, errors.Is(w.Push("/app.css", nil), http.ErrNotSupported))
+	n, err := io.Copy(w, strings.NewReader("body"))
+	assertEqual(t, nil, err)
+	assertEqual(t, int64(4), n)
+	assertEqual(t, true, w.written)
+}
+
+func TestLoadJSONRequestNoBody(t *testing.T) {
+	req := newRequest("POST", "http://localhost/pages", "")
+	req.Body = nil
+	rec := newRecorder()
+	handle(&PanicC{}, "page", "/pages", nil)(rec, req)
+	assertEqual(t, 200, rec.Code)
+	assertEqual(t, `{"page":null}`+"\n", rec.Body.String())
+}
@@ -1,6 +1,7 @@
 package rapi
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -17,6 +18,8 @@ type Request struct {
 	Action string
 	params map[string]interface{}
 
+	pathParams map[string]string
+
 	req *http.Request
 	w   http.ResponseWriter
 }
@@ -28,12 +31,40 @@ func (r *Request) Init(w http.ResponseWriter, req *http.Request, root, prefix st
 	r.Root = root
 	r.setURL(prefix)
 	r.Action = r.makeAction(extras)
-	r.params = make(map[string]interface{})
+	r.params = nil
+	if params, ok := req.Context().Value(hostKey).(map[string]string); ok {
+		r.params = make(map[string]interface{}, len(params))
+		for k, v := range params {
+			r.params[k] = v
+		}
+	}
+}
+
+// request returns base Request of controller embedding it
+func (r *Request) request() *Request {
+	return r
 }
 
 func (r *Request) makeAction(extras []string) string {
-	if r.URL.ID == "" {
-		switch r.req.Method {
+	return actionFor(r.req.Method, r.URL, extras, r.singular())
+}
+
+// singular returns true if request is served by singular resource
+func (r *Request) singular() bool {
+	return r.req.Context().Value(singularKey) != nil
+}
+
+// actionFor returns controller action name processing HTTP method for the URL.
+// HEAD is processed by GET action and PATCH by Patch action
+// if controller implements it or by Update otherwise.
+// Singular resource has no Index action and processes GET by Show.
+func actionFor(method string, u URL, extras []string, singular bool) string {
+	if method == "HEAD" {
+		method = "GET"
+	}
+
+	if u.ID == "" && !singular {
+		switch method {
 		case "GET":
 			return "Index"
 		case "POST":
@@ -41,12 +72,12 @@ func (r *Request) makeAction(extras []string) string {
 		}
 	}
 
-	if r.URL.Action != "" {
-		return r.req.Method + capitalize(r.URL.Action)
+	if u.Action != "" {
+		return method + capitalize(u.Action)
 	}
 
 	if len(extras) > 0 {
-		a := r.req.Method + capitalize(r.URL.ID)
+		a := method + capitalize(u.ID)
 		for _, v := range extras {
 			if a == v {
 				return a
@@ -54,11 +85,20 @@ func (r *Request) makeAction(extras []string) string {
 		}
 	}
 
-	switch r.req.Method {
+	if singular && method == "POST" {
+		return "Create"
+	}
+
+	switch method {
 	case "GET":
 		return "Show"
 	case "POST", "PUT":
 		return "Update"
+	case "PATCH":
+		if containsString(extras, "Patch") {
+			return "Patch"
+		}
+		return "Update"
 	case "DELETE":
 		return "Destroy"
 	}
@@ -69,6 +109,9 @@ func (r *Request) makeAction(extras []string) string {
 // LoadJSONRequest extracting JSON request by key
 // from request body into interface
 func (r *Request) LoadJSONRequest(root string, v interface{}) {
+	if r.req.Body == nil {
+		return
+	}
 	defer r.req.Body.Close()
 
 	if root == "" {
@@ -88,14 +131,77 @@ func (r *Request) QueryParam(s string) string {
 	return r.req.URL.Query().Get(s)
 }
 
-// SetParam set custom parameter for current request
+// ParamKey is the request context key of parameter set with SetParam.
+// Parameters set by middleware are readable by handlers and actions
+// through the request context:
+//    user := req.Context().Value(rapi.ParamKey("user")).(*User)
+type ParamKey string
+
+// SetParam set custom parameter for current request.
+// Parameter is stored in request context under ParamKey as well.
 func (r *Request) SetParam(k string, v interface{}) {
+	if r.params == nil {
+		r.params = make(map[string]interface{})
+	}
 	r.params[k] = v
+	r.req = r.req.WithContext(context.WithValue(r.req.Context(), ParamKey(k), v))
 }
 
 // Param returns custom parameter for current request
+// or value stored in request context under ParamKey
 func (r *Request) Param(k string) interface{} {
-	return r.params[k]
+	if v, ok := r.params[k]; ok {
+		return v
+	}
+	return r.req.Context().Value(ParamKey(k))
+}
+
+// Context returns request context. Context is canceled
+// when client disconnects or request deadline is exceeded.
+//    select {
+//    case <-p.Context().Done():
+//        return
+//    case res := <-results:
+//        p.RenderJSON(200, rapi.JSONData{p.Root: res})
+//    }
+func (r *Request) Context() context.Context {
+	return r.req.Context()
+}
+
+// WithContext replaces request context. Replaced context is passed
+// to actions and to handlers following middleware.
+//    func tenant(c rapi.Controller) bool {
+//        c.WithContext(context.WithValue(c.Context(), tenantKey, c.Header("X-Tenant")))
+//        return true
+//    }
+func (r *Request) WithContext(ctx context.Context) {
+	r.req = r.req.WithContext(ctx)
+}
+
+// PathParam returns named parameter captured from URL path by route pattern.
+// ex: for controller registered with
+//    r.Route("/users/:user_id/posts", &Posts{}, "post")
+// request to /users/10/posts/1 gives
+//    p.PathParam("user_id") // "10"
+//    p.URL.ID               // "1"
+func (r *Request) PathParam(s string) string {
+	return r.pathParams[s]
+}
+
+// URLFor returns path of the named route registered in the router
+// serving current request. Path is prefixed with the router mount path
+// if router is mounted. See Router.URL and Route.Mount.
+//    p.URLFor("pages", "id", "10")
+func (r *Request) URLFor(name string, params ...string) (string, error) {
+	router := servingRouter(r.req)
+	if router == nil {
+		return "", fmt.Errorf("rapi: request is not served by router")
+	}
+	u, err := router.URL(name, params...)
+	if err != nil {
+		return "", err
+	}
+	return MountPath(r.req) + u, nil
 }
 
 // Header returns request header
@@ -110,11 +216,16 @@ func (r *Request) CurrentAction() string {
 
 // RenderJSON rendering JSON to client
 func (r *Request) RenderJSON(code int, s JSONData) {
+	r.renderJSON(code, s)
+}
+
+// renderJSON renders any value in JSON format gzipped if client accepts it
+func (r *Request) renderJSON(code int, v interface{}) {
 	if strings.Contains(r.req.Header.Get("Accept-Encoding"), "gzip") {
-		RenderJSONgzip(r.w, code, s)
+		writeJSONgzip(r.w, code, v)
 		return
 	}
-	RenderJSON(r.w, code, s)
+	writeJSON(r.w, code, v)
 }
 
 // RenderJSONError rendering error to client in JSON format
@@ -152,19 +263,35 @@ func (r *Request) LoadFile(field, dir string) (string, error) {
 }
 
 func (r *Request) setURL(prefix string) {
-	path := strings.TrimPrefix(r.req.URL.Path, prefix)
+	r.URL, r.pathParams = splitURL(prefix, r.req.URL.Path, r.singular())
+}
+
+// splitURL extracts ID and action following the prefix pattern from the path
+// and parameters captured by the pattern. Singular resource path has action only.
+func splitURL(prefix, p string, singular bool) (URL, map[string]string) {
+	params, path, ok := matchPattern(prefix, p)
+	if !ok {
+		path = strings.TrimPrefix(p, prefix)
+	}
 	path = strings.TrimPrefix(path, "/")
 	path = strings.TrimSuffix(path, "/")
 	parts := strings.Split(path, "/")
 
+	var u URL
 	l := len(parts)
 
+	if singular {
+		u.Action = parts[0]
+		return u, params
+	}
+
 	if l > 0 {
-		r.URL.ID = parts[0]
+		u.ID = parts[0]
 		if l > 1 {
-			r.URL.Action = parts[1]
+			u.Action = parts[1]
 		}
 	}
+	return u, params
 }
 
 // URL storing id and action from url
@@ -1,9 +1,15 @@
 package rapi
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"io"
+	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"reflect"
 	"runtime"
 	"strings"
 	"testing"
@@ -89,6 +95,22 @@ func TestMakeAction(t *testing.T) {
 	assertEqual(t, int64(10), r.URL.ID64())
 	assertEqual(t, "edit", r.URL.Action)
 
+	r = newReq(httpWriter, newRequest("PATCH", "http://localhost/pages/10", "{}"), "root", p)
+	assertEqual(t, "Update", r.Action)
+
+	r = &Request{}
+	r.Init(httpWriter, newRequest("PATCH", "http://localhost/pages/10", "{}"), "root", p, []string{"Patch"})
+	assertEqual(t, "Patch", r.Action)
+
+	r = newReq(httpWriter, newRequest("PATCH", "http://localhost/pages/10/edit", "{}"), "root", p)
+	assertEqual(t, "PATCHEdit", r.Action)
+
+	r = newReq(httpWriter, newRequest("HEAD", "http://localhost/pages/10", "{}"), "root", p)
+	assertEqual(t, "Show", r.Action)
+
+	r = newReq(httpWriter, newRequest("HEAD", "http://localhost/pages", "{}"), "root", p)
+	assertEqual(t, "Index", r.Action)
+
 	r = newReq(httpWriter, newRequest("DELETE", "http://localhost/pages/10", "{}"), "root", p)
 	assertEqual(t, "Destroy", r.Action)
 	assertEqual(t, "10", r.URL.ID)
@@ -102,6 +124,20 @@ func TestMakeAction(t *testing.T) {
 	assertEqual(t, "edit", r.URL.Action)
 }
 
+func TestPathParam(t *testing.T) {
+	p := "/users/:user_id/posts"
+	r := newReq(httpWriter, newRequest("GET", "http://localhost/users/5/posts/10/edit", "{}"), "root", p)
+	assertEqual(t, "GETEdit", r.Action)
+	assertEqual(t, "5", r.PathParam("user_id"))
+	assertEqual(t, "10", r.URL.ID)
+	assertEqual(t, "edit", r.URL.Action)
+
+	r = newReq(httpWriter, newRequest("POST", "http://localhost/users/5/posts", "{}"), "root", p)
+	assertEqual(t, "Create", r.Action)
+	assertEqual(t, "5", r.PathParam("user_id"))
+	assertEqual(t, "", r.PathParam("id"))
+}
+
 func TestQueryParams(t *testing.T) {
 	req := newRequest("GET", "http://localhost/?p1=1&p2=2", "{}")
 	r := Request{}
@@ -168,6 +204,18 @@ func (t *TestC) Create() {
 	t.RenderJSON(200, JSONData{t.Root: i})
 }
 
+func TestControllerActions(t *testing.T) {
+	ct := newController(&TestC{}, "page", "/pages", implements(&TestC{}), nil)
+	typ := reflect.TypeOf(&TestC{})
+	for _, a := range []string{"Index", "Show", "Create", "PUTPublish", "Patch"} {
+		act, ok := ct.actions[a]
+		assertEqual(t, true, ok)
+		assertEqual(t, a, typ.Method(act.index).Name)
+	}
+	_, ok := ct.actions["Destroy"]
+	assertEqual(t, false, ok)
+}
+
 func TestReponseIndex(t *testing.T) {
 	req := newRequest("GET", "http://localhost/pages/", "{}")
 	handler := handle(&TestC{}, "page", "/pages", implements(&TestC{}))
@@ -192,10 +240,42 @@ func TestReponseCreate(t *testing.T) {
 	assertEqual(t, "{\"page\":[{\"id\":1}]}\n", string(rec.Body.Bytes()))
 }
 
+func (t *TestC) PUTPublish() {
+	t.RenderJSON(200, JSONData{t.Root: "publish"})
+}
+
+func (t *TestC) Patch() {
+	t.RenderJSON(200, JSONData{t.Root: "patch"})
+}
+
+func TestReponsePatch(t *testing.T) {
+	req := newRequest("PATCH", "http://localhost/pages/10", "{}")
+	handler := handle(&TestC{}, "page", "/pages", implements(&TestC{}))
+	rec := newRecorder()
+	handler(rec, req)
+	assertEqual(t, "{\"page\":\"patch\"}\n", string(rec.Body.Bytes()))
+
+	req = newRequest("PUT", "http://localhost/pages/publish", "{}")
+	rec = newRecorder()
+	handler(rec, req)
+	assertEqual(t, "{\"page\":\"publish\"}\n", string(rec.Body.Bytes()))
+}
+
+func TestReponseHead(t *testing.T) {
+	req := newRequest("HEAD", "http://localhost/pages/10", "{}")
+	handler := handle(&TestC{}, "page", "/pages", implements(&TestC{}))
+	rec := newRecorder()
+	handler(rec, req)
+	assertEqual(t, 200, rec.Code)
+	assertEqual(t, "application/json; charset=utf-8", rec.Header().Get("Content-Type"))
+	assertEqual(t, "", string(rec.Body.Bytes()))
+}
+
 func BenchmarkHandleIndex(b *testing.B) {
 	req := newRequest("GET", "http://localhost/pages/", "{}")
 	handler := handle(&TestC{}, "page", "/pages", implements(&TestC{}))
 
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		handler(newRecorder(), req)
 	}
@@ -205,6 +285,7 @@ func BenchmarkHandleShow(b *testing.B) {
 	req := newRequest("GET", "http://localhost/pages/10", "{}")
 	handler := handle(&TestC{}, "page", "/pages", implements(&TestC{}))
 
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		handler(newRecorder(), req)
 	}
@@ -214,7 +295,161 @@ func BenchmarkHandleCreate(b *testing.B) {
 	req := newRequest("POST", "http://localhost/pages/", `{"root":[{"id":1}]}`)
 	handler := handle(&TestC{}, "page", "/pages", implements(&TestC{}))
 
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		handler(newRecorder(), req)
+	}
+}
+
+func BenchmarkHandleCustom(b *testing.B) {
+	req := newRequest("PUT", "http://localhost/pages/publish", "{}")
+	handler := handle(&TestC{}, "page", "/pages", implements(&TestC{}))
+
+	b.ReportAllocs()
 	for n := 0; n < b.N; n++ {
 		handler(newRecorder(), req)
 	}
 }
+
+func BenchmarkRouterController(b *testing.B) {
+	req := newRequest("GET", "http://localhost/api/pages/10", "{}")
+	r := NewRouter()
+	r.PathPrefix("/api").Route("/pages", &TestC{}, "page")
+
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		r.ServeHTTP(newRecorder(), req)
+	}
+}
+
+type ctxKey struct{}
+
+type ContextC struct {
+	Request
+}
+
+func (c *ContextC) Index(ctx context.Context) {
+	c.RenderJSON(200, JSONData{
+		"user":   ctx.Value(ParamKey("user")),
+		"tenant": ctx.Value(ctxKey{}),
+		"param":  c.Param("user"),
+	})
+}
+
+func (c *ContextC) Show() {
+	select {
+	case <-c.Context().Done():
+		c.RenderString(499, c.Context().Err().Error())
+	default:
+		c.RenderString(200, "ok")
+	}
+}
+
+func TestRequestContext(t *testing.T) {
+	setUser := func(c Controller) bool {
+		c.SetParam("user", "alice")
+		c.WithContext(context.WithValue(c.Context(), ctxKey{}, "acme"))
+		return true
+	}
+
+	r := NewRouter()
+	r.Route("/pages", &ContextC{}, "page", setUser)
+	r.NewRoute("").Use(setUser).HandleFunc("/info", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.Context().Value(ParamKey("user")).(string) + " " + req.Context().Value(ctxKey{}).(string)))
+	})
+
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages", ""))
+	assertEqual(t, `{"param":"alice","tenant":"acme","user":"alice"}`+"\n", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/info", ""))
+	assertEqual(t, "alice acme", rec.Body.String())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages/1", "").WithContext(ctx))
+	assertEqual(t, 499, rec.Code)
+	assertEqual(t, "context canceled", rec.Body.String())
+}
+
+func TestParamFromContext(t *testing.T) {
+	req := newRequest("GET", "http://localhost/pages", "")
+	req = req.WithContext(context.WithValue(req.Context(), ParamKey("user"), "bob"))
+	r := newReq(httpWriter, req, "page", "/pages")
+	assertEqual(t, "bob", r.Param("user"))
+	assertEqual(t, nil, r.Param("tenant"))
+}
+
+// hookTrace is the trace of HookC hooks recorded by the last Finalize
+var hookTrace string
+
+type HookC struct {
+	Request
+	trace []string
+}
+
+func (c *HookC) BeforeAction() bool {
+	c.trace = append(c.trace, "before")
+	if c.QueryParam("deny") != "" {
+		c.RenderJSONError(http.StatusForbidden, "forbidden")
+		return false
+	}
+	return true
+}
+
+func (c *HookC) AfterAction() {
+	c.trace = append(c.trace, "after")
+}
+
+func (c *HookC) Finalize() {
+	c.trace = append(c.trace, "finalize")
+	hookTrace = strings.Join(c.trace, " ")
+}
+
+func (c *HookC) HandleError(err error) {
+	c.trace = append(c.trace, "error")
+	c.RenderJSONError(http.StatusTeapot, err.Error())
+}
+
+func (c *HookC) Index() {
+	c.trace = append(c.trace, "index")
+	c.RenderJSON(200, JSONData{c.Root: "all"})
+}
+
+func (c *HookC) Show(id int64) error {
+	c.trace = append(c.trace, "show")
+	if id == 0 {
+		panic("show failed")
+	}
+	return errors.New("failed")
+}
+
+func TestControllerHooks(t *testing.T) {
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+
+	r := NewRouter()
+	r.Route("/pages", &HookC{}, "page")
+
+	for _, v := range []struct {
+		url   string
+		code  int
+		resp  string
+		trace string
+	}{
+		{"/pages", 200, `{"page":"all"}`, "before index after finalize"},
+		{"/pages?deny=1", 403, `{"errors":{"message":["forbidden"]}}`, "before finalize"},
+		{"/pages/1", 418, `{"errors":{"message":["failed"]}}`, "before show error after finalize"},
+		{"/pages/x", 418, `{"errors":{"message":["invalid id"]}}`, "before error after finalize"},
+		{"/pages/0", 500, `{"errors":{"message":["Internal Server Error"]}}`, "before show finalize"},
+	} {
+		hookTrace = ""
+		rec := newRecorder()
+		r.ServeHTTP(rec, newRequest("GET", "http://localhost"+v.url, ""))
+		assertEqual(t, v.code, rec.Code)
+		assertEqual(t, v.resp+"\n", rec.Body.String())
+		assertEqual(t, v.trace, hookTrace)
+	}
+}
@@ -1,16 +1,38 @@
 package rapi
 
-import "net/http"
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
 
+// Route is the group of routes or the registered route. Router serves
+// snapshots of registered routes, changing registered route publishes
+// its new snapshot, so it is safe while serving requests.
 type Route struct {
 	router  *Router
 	prefix  string
 	handler http.Handler
+	kind    RouteKind
+
+	ctrl     *controller // controller serving route actions
+	resource *Route      // parent resource route of nested route
+	funcs    []ReqFunc   // middleware inherited by nested routes
+	versions *Versions   // API versions mounted at route
+	origin   *Route      // route the published snapshot is taken of
+
+	methods []string // HTTP methods served by route, any if empty
+	exact   bool     // matching whole path only
+	params  []string // names of parameters in prefix pattern
 }
 
 // HandleFunc setting function to handle route
-func (r *Route) HandleFunc(s string, f func(http.ResponseWriter, *http.Request)) {
-	r.NewRoute(s).HandlerFunc(f).addRoute(r.prefix == "")
+// Optional methods restrict route to the given HTTP methods.
+// ex:
+//    r.HandleFunc("/login", loginHandler, "POST")
+func (r *Route) HandleFunc(s string, f func(http.ResponseWriter, *http.Request), methods ...string) *Route {
+	return r.NewRoute(s).HandlerFunc(f).Methods(methods...).addRoute(r.prefix == "")
 }
 
 // Route registers a new route with a matcher for URL path
@@ -24,9 +46,124 @@ func (r *Route) HandleFunc(s string, f func(http.ResponseWriter, *http.Request))
 //  - "page" is the root key for json request/response
 //  - AuthFunc is middleware function that implements ReqFunc.
 //
-func (r *Route) Route(path string, i Controller, rootKey string, funcs ...ReqFunc) {
-	rt := r.NewRoute(path)
-	rt.HandlerFunc(handle(i, rootKey, rt.prefix, implements(i), funcs...)).addRoute(false)
+func (r *Route) Route(path string, i Controller, rootKey string, funcs ...ReqFunc) *Route {
+	return r.route(path, i, rootKey, false, funcs, nil)
+}
+
+// RouteFunc registers controller created by the factory function
+// for every request. See Route.Route.
+// ex:
+//    api.RouteFunc("/pages", func() rapi.Controller {
+//        return &Pages{db: db}
+//    }, "page")
+func (r *Route) RouteFunc(path string, f func() Controller, rootKey string, funcs ...ReqFunc) *Route {
+	return r.route(path, f(), rootKey, false, funcs, f)
+}
+
+// Resource registers controller for the resource path and returns route
+// for registering nested resources beneath the resource ID.
+// JSON root key and ID parameter name are taken from the singular
+// form of the last path segment.
+// ex:
+//    r.Resource("/users", &Users{}).Resource("/posts", &Posts{})
+// registers
+//  - Users controller on /users with "user" root key
+//  - Posts controller on /users/:user_id/posts with "post" root key
+//
+// Nested route inherits middleware of the resource, its Name, Only
+// and Except apply to the resource route.
+func (r *Route) Resource(path string, i Controller, funcs ...ReqFunc) *Route {
+	name := singularize(lastSegment(path))
+	rt := r.route(path, i, name, false, funcs, nil)
+	nested := rt.NewRoute("/:" + name + "_id")
+	nested.resource = rt
+	return nested
+}
+
+// SingularResource registers controller for the resource having no ID
+// and returns route for registering nested resources.
+// Requests are processed by actions:
+//  - GET /profile          Show
+//  - POST /profile         Create
+//  - PUT /profile          Update
+//  - PATCH /profile        Patch or Update
+//  - DELETE /profile       Destroy
+//  - GET /profile/avatar   GETAvatar
+//
+// ex:
+//    r.SingularResource("/profile", &Profile{})
+func (r *Route) SingularResource(path string, i Controller, funcs ...ReqFunc) *Route {
+	rt := r.route(path, i, singularize(lastSegment(path)), true, funcs, nil)
+	nested := rt.NewRoute("")
+	nested.resource = rt
+	return nested
+}
+
+// Only restricts controller registered on the route to the given actions.
+// Other actions are not dispatched even if controller implements them.
+// Applies to the resource controller for routes returned by Resource.
+// Restrictions of several calls are combined.
+// ex:
+//    public.Route("/pages", &Pages{}, "page").Only("Index", "Show")
+func (r *Route) Only(actions ...string) *Route {
+	return r.restrict(actions, true)
+}
+
+// Except restricts controller registered on the route to all actions
+// except the given ones. See Route.Only.
+//    public.Route("/pages", &Pages{}, "page").Except("Destroy")
+func (r *Route) Except(actions ...string) *Route {
+	return r.restrict(actions, false)
+}
+
+func (r *Route) restrict(actions []string, only bool) *Route {
+	rt := r
+	if rt.ctrl == nil && rt.resource != nil {
+		rt = rt.resource
+	}
+	ct := rt.ctrl
+	if ct == nil {
+		r.router.reportError(fmt.Errorf("rapi: route %s has no controller to restrict actions", r.prefix))
+		return r
+	}
+
+	for _, v := range actions {
+		if _, ok := ct.actions[v]; !ok {
+			r.router.reportError(fmt.Errorf("rapi: controller *%s has no action %s", ct.typ, v))
+		}
+	}
+
+	c := *ct
+	c.actions = make(map[string]*action)
+	for k, a := range ct.actions {
+		if containsString(actions, k) == only {
+			c.actions[k] = a
+		}
+	}
+	c.extras = []string{}
+	for _, v := range ct.extras {
+		if _, ok := c.actions[v]; ok {
+			c.extras = append(c.extras, v)
+		}
+	}
+
+	rt.ctrl, rt.handler = &c, &c
+	if !rt.router.update(rt) {
+		r.router.reportError(fmt.Errorf("rapi: route %s is not registered to restrict actions", rt.prefix))
+	}
+	return r
+}
+
+func (r *Route) route(path string, i Controller, rootKey string, singular bool, funcs []ReqFunc, factory func() Controller) *Route {
+	rt := r.NewRoute(path).Use(funcs...)
+	rt.kind = KindController
+	rt.ctrl = newController(i, rootKey, rt.prefix, implements(i), rt.funcs)
+	rt.ctrl.singular = singular
+	rt.ctrl.factory = factory
+	for _, err := range rt.ctrl.bind(r.router.services) {
+		r.router.reportError(err)
+	}
+	return rt.Handler(rt.ctrl).addRoute(false)
 }
 
 // FileServer provides static files serving
@@ -40,19 +177,59 @@ func (r *Route) Route(path string, i Controller, rootKey string, funcs ...ReqFun
 //  - dirIndex specifying if it should display directory content or not
 //  - preferGzip specifying if it should look for gzipped file version
 //
-func (r *Route) FileServer(path string, b ...bool) {
-	r.Handler(fileServer(path, b)).addRoute(false)
+func (r *Route) FileServer(path string, b ...bool) *Route {
+	r.kind = KindFileServer
+	return r.Handler(fileServer(path, b)).addRoute(false)
 }
 
 // NewRoute registers an empty route.
+// New route inherits middleware of the parent route.
 func (r *Route) NewRoute(prefix string) *Route {
-	return &Route{router: r.router, prefix: cleanPath(r.prefix + prefix)}
+	return &Route{router: r.router, prefix: cleanPath(r.prefix + prefix), funcs: r.funcs}
+}
+
+// Use appends middleware to the route. Middleware is inherited by every
+// controller, handler and file server registered beneath the route
+// and runs before the ones added in nested routes.
+// ex:
+//    api := r.PathPrefix("/api/v1").Use(authenticate)
+//    api.Route("/pages", &Pages{}, "page")
+//    api.HandleFunc("/stats", statsHandler)
+func (r *Route) Use(funcs ...ReqFunc) *Route {
+	r.funcs = append(r.funcs[:len(r.funcs):len(r.funcs)], funcs...)
+	if r.ctrl != nil {
+		ct := *r.ctrl
+		ct.funcs = r.funcs
+		r.ctrl, r.handler = &ct, &ct
+	}
+	return r.update()
+}
+
+// Name sets the route name used for URL generation. See Router.URL.
+// Route returned by Resource names the resource route.
+//    api.Route("/pages", &Pages{}, "page").Name("pages")
+func (r *Route) Name(name string) *Route {
+	rt := r
+	if rt.ctrl == nil && rt.resource != nil {
+		rt = rt.resource
+	}
+	r.router.setName(name, rt)
+	return r
 }
 
 // Handler sets a handler for the route.
 func (r *Route) Handler(handler http.Handler) *Route {
 	r.handler = handler
-	return r
+	return r.update()
+}
+
+// Methods sets HTTP methods served by the route.
+func (r *Route) Methods(methods ...string) *Route {
+	r.methods = nil
+	for _, v := range methods {
+		r.methods = append(r.methods, strings.ToUpper(v))
+	}
+	return r.update()
 }
 
 // HandlerFunc sets a handler function for the route.
@@ -60,11 +237,91 @@ func (r *Route) HandlerFunc(f func(http.ResponseWriter, *http.Request)) *Route {
 	return r.Handler(http.HandlerFunc(f))
 }
 
+// pathParams maps captured path values to the route parameter names
+func (r *Route) pathParams(vals []string) map[string]string {
+	if len(vals) == 0 {
+		return nil
+	}
+	params := make(map[string]string, len(vals))
+	for i, v := range vals {
+		if i < len(r.params) {
+			params[r.params[i]] = v
+		}
+	}
+	return params
+}
+
+// url builds the route path filling pattern parameters from params.
+// For controller routes "id" and "action" params are appended
+// as ID and custom action segments.
+func (r *Route) url(name string, params map[string]string) (string, error) {
+	segs := strings.Split(r.prefix, "/")
+	for i, v := range segs {
+		if len(v) < 2 || (v[0] != ':' && v[0] != '*') {
+			continue
+		}
+		p, ok := params[v[1:]]
+		if !ok {
+			return "", fmt.Errorf("rapi: missing parameter %q for route %q", v[1:], name)
+		}
+		if v[0] == ':' {
+			if p == "" {
+				return "", fmt.Errorf("rapi: empty parameter %q for route %q", v[1:], name)
+			}
+			p = url.PathEscape(p)
+		}
+		segs[i] = p
+	}
+	res := strings.Join(segs, "/")
+
+	if r.ctrl == nil {
+		return res, nil
+	}
+
+	if id := params["id"]; id != "" && !r.ctrl.singular && !containsString(r.params, "id") {
+		res = strings.TrimSuffix(res, "/") + "/" + url.PathEscape(id)
+	}
+	if a := params["action"]; a != "" {
+		if !r.hasAction(a) {
+			return "", fmt.Errorf("rapi: unknown action %q for route %q", a, name)
+		}
+		res = strings.TrimSuffix(res, "/") + "/" + url.PathEscape(a)
+	}
+	return res, nil
+}
+
+// hasAction returns true if controller has custom action for the URL segment
+func (r *Route) hasAction(a string) bool {
+	for _, m := range meths {
+		if containsString(r.ctrl.extras, m+capitalize(a)) {
+			return true
+		}
+	}
+	return false
+}
+
 func (r *Route) addRoute(named bool) *Route {
-	if named {
-		r.router.addNamedRoute(r)
-	} else {
-		r.router.addRoute(r)
+	if r.kind == "" {
+		r.kind = KindHandler
 	}
+	r.router.addRoute(r, named)
+	return r
+}
+
+// update publishes the route again if it is already registered.
+// Routes served by router are snapshots never modified once published,
+// so registered route can be changed while serving requests.
+func (r *Route) update() *Route {
+	r.router.update(r)
 	return r
 }
+
+// snapshot returns copy of the route to be published
+func (r *Route) snapshot() *Route {
+	c := *r
+	c.origin = r
+	if c.ctrl == nil && len(c.funcs) > 0 {
+		c.handler = reqFuncsHandler(c.handler, c.funcs)
+	}
+	return &c
+}
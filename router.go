@@ -1,39 +1,171 @@
 package rapi
 
 import (
+	"context"
+	"fmt"
 	"net/http"
 	"reflect"
 	"sort"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// RedirectPolicy defines how router serves requests for non canonical paths
+type RedirectPolicy int
+
+const (
+	// RedirectOff serves request path as is
+	RedirectOff RedirectPolicy = iota
+	// Redirect301 responds with 301 Moved Permanently redirect
+	Redirect301
+	// Redirect308 responds with 308 Permanent Redirect preserving method and body
+	Redirect308
+	// RedirectRewrite serves canonical path without redirect
+	RedirectRewrite
 )
 
 type Router struct {
-	routes      map[string]http.Handler
-	namedRoutes map[string]http.Handler
-	keys        []string
-	namedKeys   []string
+	// NotFound handles requests not matching any route.
+	// Handler of the parent router is used for host, version
+	// and mounted routers if not set, JSON error is rendered otherwise.
+	NotFound http.Handler
+	// MethodNotAllowed handles requests with method not served by matched route.
+	// Allow header is set before handler call. Like NotFound it falls back
+	// to the parent router handler and to JSON error.
+	MethodNotAllowed http.Handler
+	// CleanPath is the policy for paths containing . and .. elements
+	// or repeated slashes, Redirect301 by default.
+	// Host and version sub-routers copy policies of the router when created.
+	CleanPath RedirectPolicy
+	// TrailingSlash is the policy for paths not matching any route but matching
+	// the route with trailing slash added or removed, RedirectOff by default.
+	TrailingSlash RedirectPolicy
+	// ErrorHandler renders errors returned by controller actions.
+	// Like NotFound it falls back to the parent router handler,
+	// JSON error with HTTPError code or 500 is rendered if not set.
+	ErrorHandler func(w http.ResponseWriter, req *http.Request, err error)
+	// OnPanic is called with the recovered value and stack trace
+	// when serving request panics, for crash reporting.
+	// Panic is recovered by the outermost router serving request,
+	// hook of the innermost host, version or mounted router having it is called.
+	OnPanic func(req *http.Request, v interface{}, stack []byte)
+	// Strict makes registration panic on the first route error.
+	// See Router.Validate.
+	Strict bool
+
+	mu         sync.Mutex // guarding registration
+	table      atomic.Pointer[table]
+	errs       []error // registration errors
+	middleware []func(http.Handler) http.Handler
+	services   *services // shared with host and version sub-routers
 }
 
 func NewRouter() *Router {
-	return &Router{namedRoutes: make(map[string]http.Handler), routes: make(map[string]http.Handler)}
+	r := &Router{CleanPath: Redirect301, services: &services{}}
+	r.table.Store(&table{tree: &node{}, names: make(map[string]*Route)})
+	return r
+}
+
+// subRouter returns router for host or API version sharing services,
+// path policies and Strict mode of the router
+func (r *Router) subRouter() *Router {
+	sub := NewRouter()
+	sub.services = r.services
+	sub.CleanPath, sub.TrailingSlash = r.CleanPath, r.TrailingSlash
+	sub.Strict = r.Strict
+	return sub
 }
 
 // HandleFunc registers a new route with a matcher for the URL path.
+// Optional methods restrict route to the given HTTP methods.
 // See Route.HandlerFunc().
-func (r *Router) HandleFunc(path string, f func(http.ResponseWriter, *http.Request)) {
-	r.NewRoute("").HandleFunc(path, f)
+func (r *Router) HandleFunc(path string, f func(http.ResponseWriter, *http.Request), methods ...string) *Route {
+	return r.NewRoute("").HandleFunc(path, f, methods...)
 }
 
 // Route registers a new route with a matcher for URL path
-// and registering controller handler
-func (r *Router) Route(path string, i Controller, rootKey string, funcs ...ReqFunc) {
-	route := r.NewRoute(path)
-	route.HandlerFunc(handle(i, rootKey, route.prefix, implements(i), funcs...)).addRoute(false)
+// and registering controller handler. See Route.Route().
+func (r *Router) Route(path string, i Controller, rootKey string, funcs ...ReqFunc) *Route {
+	return r.NewRoute("").Route(path, i, rootKey, funcs...)
+}
+
+// RouteFunc registers controller created by the factory function
+// for every request. See Route.RouteFunc().
+func (r *Router) RouteFunc(path string, f func() Controller, rootKey string, funcs ...ReqFunc) *Route {
+	return r.NewRoute("").RouteFunc(path, f, rootKey, funcs...)
+}
+
+// Resource registers controller for the resource path and returns route
+// for nested resources. See Route.Resource().
+func (r *Router) Resource(path string, i Controller, funcs ...ReqFunc) *Route {
+	return r.NewRoute("").Resource(path, i, funcs...)
+}
+
+// SingularResource registers controller for the resource having no ID.
+// See Route.SingularResource().
+func (r *Router) SingularResource(path string, i Controller, funcs ...ReqFunc) *Route {
+	return r.NewRoute("").SingularResource(path, i, funcs...)
 }
 
 // HandlePrefix registers a new handler to serve prefix
-func (r *Router) HandlePrefix(path string, handler http.Handler) {
-	r.NewRoute(path).Handler(handler).addRoute(false)
+// Optional methods restrict route to the given HTTP methods.
+func (r *Router) HandlePrefix(path string, handler http.Handler, methods ...string) *Route {
+	return r.NewRoute(path).Handler(handler).Methods(methods...).addRoute(false)
+}
+
+// Mount registers handler serving all requests beneath the prefix
+// with the prefix stripped. See Route.Mount().
+func (r *Router) Mount(prefix string, h http.Handler) *Route {
+	return r.NewRoute("").Mount(prefix, h)
+}
+
+// URL returns path of the named route. Params are key/value pairs
+// filling route pattern parameters. For controller routes "id" and "action"
+// keys set ID and custom action segments. ex:
+//    r.Route("/users/:user_id/posts", &Posts{}, "post").Name("posts")
+//    r.URL("posts", "user_id", "1", "id", "2", "action", "publish")
+//    // "/users/1/posts/2/publish"
+func (r *Router) URL(name string, params ...string) (string, error) {
+	if len(params)%2 != 0 {
+		return "", fmt.Errorf("rapi: odd number of parameters for route %q", name)
+	}
+	m := make(map[string]string, len(params)/2)
+	for i := 0; i < len(params); i += 2 {
+		m[params[i]] = params[i+1]
+	}
+	return r.url(name, m)
+}
+
+// url builds path of the named route of the router or of the latest
+// API version having it, prefixed with the version path segment
+func (r *Router) url(name string, params map[string]string) (string, error) {
+	t := r.load()
+	if rt, ok := t.names[name]; ok {
+		return rt.url(name, params)
+	}
+
+	for _, rt := range t.routes {
+		if rt.versions == nil {
+			continue
+		}
+		for _, ver := range rt.versions.list() {
+			if _, ok := ver.router.load().names[name]; !ok {
+				continue
+			}
+			prefix, err := rt.url(name, params)
+			if err != nil {
+				return "", err
+			}
+			p, err := ver.router.url(name, params)
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSuffix(prefix, "/") + "/v" + strconv.Itoa(ver.number) + p, nil
+		}
+	}
+	return "", fmt.Errorf("rapi: route %q not found", name)
 }
 
 // NewRoute registers an empty route.
@@ -41,90 +173,423 @@ func (r *Router) NewRoute(prefix string) *Route {
 	return &Route{router: r, prefix: prefix}
 }
 
-func (r *Router) addRoute(rt *Route) {
-	r.routes[rt.prefix] = rt.handler
-	r.setKeys()
+// addRoute adds route into the routes tree. Exact routes match only
+// the whole path, others match any path beneath the route prefix.
+// Route registered with the same prefix, kind and methods replaces existing one.
+func (r *Router) addRoute(rt *Route, exact bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.publish(rt, exact)
 }
 
-func (r *Router) addNamedRoute(rt *Route) {
-	r.namedRoutes[rt.prefix] = rt.handler
-	r.setNamedKeys()
+// update publishes changed route again and returns true if it is registered
+func (r *Router) update(rt *Route) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.registered(rt) == nil {
+		return false
+	}
+	r.publish(rt, rt.exact)
+	return true
 }
 
-func (r *Router) setKeys() {
-	r.keys = make([]string, len(r.routes))
-	for key := range r.routes {
-		r.keys = append(r.keys, key)
+// registered returns published copy of the route or nil, r.mu must be held
+func (r *Router) registered(rt *Route) *Route {
+	for _, v := range r.load().routes {
+		if v.origin == rt {
+			return v
+		}
 	}
-	sort.Sort(sort.Reverse(sort.StringSlice(r.keys)))
+	return nil
 }
 
-func (r *Router) setNamedKeys() {
-	r.namedKeys = make([]string, len(r.namedRoutes))
-	for key := range r.namedRoutes {
-		r.namedKeys = append(r.namedKeys, key)
+// publish stores table with snapshot of the route replacing its previous
+// one and routes duplicating it, r.mu must be held
+func (r *Router) publish(rt *Route, exact bool) {
+	rt.exact = exact
+	rt.params = paramNames(rt.prefix)
+	pub := rt.snapshot()
+
+	t := r.load().clone()
+	routes := make([]*Route, 0, len(t.routes)+1)
+	for _, v := range t.routes {
+		if v.origin == rt {
+			continue
+		}
+		if v.prefix == rt.prefix && v.exact == exact && sameMethods(v.methods, rt.methods) {
+			r.addError(fmt.Errorf("rapi: duplicate route %s replaces previously registered one", rt.prefix))
+			continue
+		}
+		routes = append(routes, v)
+	}
+	t.setRoutes(append(routes, pub))
+
+	t.names = make(map[string]*Route, len(t.names))
+	for k, v := range r.load().names {
+		if v.origin == rt {
+			v = pub
+		}
+		t.names[k] = v
+	}
+	r.table.Store(t)
+
+	if r.Strict {
+		if err := r.validate(); err != nil {
+			panic(err)
+		}
 	}
-	sort.Sort(sort.Reverse(sort.StringSlice(r.namedKeys)))
 }
 
-func (r *Router) matchNamed(path string) string {
-	for _, v := range r.namedKeys {
-		if v == path {
-			return v
+// Remove unregisters all routes with the prefix and returns true
+// if any route is removed. It is safe to call while serving requests.
+//    r.Remove("/api/tenants/acme")
+func (r *Router) Remove(prefix string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	t := r.load().clone()
+	routes := make([]*Route, 0, len(t.routes))
+	for _, v := range t.routes {
+		if v.prefix != prefix {
+			routes = append(routes, v)
 		}
 	}
-	return ""
+	if len(routes) == len(t.routes) {
+		return false
+	}
+
+	names := t.names
+	t.names = make(map[string]*Route, len(names))
+	for k, v := range names {
+		if v.prefix != prefix {
+			t.names[k] = v
+		}
+	}
+	t.setRoutes(routes)
+	r.table.Store(t)
+	return true
 }
 
-func (r *Router) matchCommon(path string) string {
-	for _, v := range r.keys {
-		if strings.HasPrefix(path, v) {
-			return v
+// setName publishes table naming the route, previous name of the route
+// is dropped. Name of route not registered yet refers to its snapshot.
+func (r *Router) setName(name string, rt *Route) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pub := r.registered(rt)
+	if pub == nil {
+		pub = rt.snapshot()
+	}
+
+	t := r.load().clone()
+	names := t.names
+	t.names = make(map[string]*Route, len(names)+1)
+	for k, v := range names {
+		if v.origin != rt {
+			t.names[k] = v
 		}
 	}
-	return ""
+	t.names[name] = pub
+	r.table.Store(t)
 }
 
-func (r *Router) match(path string) http.Handler {
-	if k := r.matchNamed(path); k != "" {
-		return r.namedRoutes[k]
+// load returns current routing table
+func (r *Router) load() *table {
+	return r.table.Load()
+}
+
+// lookup returns routes matching the path and values of path parameters
+func (r *Router) lookup(path string) ([]*Route, []string) {
+	return r.load().tree.lookup(path, nil)
+}
+
+// has returns true if router serves the path directly
+// or redirecting it by trailing slash policy
+func (r *Router) has(path string) bool {
+	if rts, _ := r.lookup(path); len(rts) > 0 {
+		return true
+	}
+	if p := toggleSlash(path); p != "" && r.TrailingSlash != RedirectOff {
+		rts, _ := r.lookup(p)
+		return len(rts) > 0
 	}
+	return false
+}
 
-	if k := r.matchCommon(path); k != "" {
-		return r.routes[k]
+// notFound renders 404 response with NotFound handler
+// of the routers serving request
+func notFound(w http.ResponseWriter, req *http.Request) {
+	if r := findRouter(req, func(r *Router) bool { return r.NotFound != nil }); r != nil {
+		r.NotFound.ServeHTTP(w, req)
+		return
 	}
+	RenderJSONError(w, http.StatusNotFound, "not found")
+}
 
-	return http.NotFoundHandler()
+// methodNotAllowed renders 405 response with MethodNotAllowed handler
+// of the routers serving request
+func methodNotAllowed(w http.ResponseWriter, req *http.Request) {
+	if r := findRouter(req, func(r *Router) bool { return r.MethodNotAllowed != nil }); r != nil {
+		r.MethodNotAllowed.ServeHTTP(w, req)
+		return
+	}
+	RenderJSONError(w, http.StatusMethodNotAllowed, "method not allowed")
+}
+
+// routerChain is the list of routers serving request, innermost first.
+// Host, version and mounted routers are served beneath their parents.
+type routerChain struct {
+	router *Router
+	parent *routerChain
+}
+
+// withRouter returns request served by the router beneath routers
+// already serving it
+func withRouter(req *http.Request, r *Router) *http.Request {
+	parent, _ := req.Context().Value(routerKey).(*routerChain)
+	return req.WithContext(context.WithValue(req.Context(), routerKey, &routerChain{router: r, parent: parent}))
+}
+
+// servingRouter returns the innermost router serving request or nil
+func servingRouter(req *http.Request) *Router {
+	if c, ok := req.Context().Value(routerKey).(*routerChain); ok {
+		return c.router
+	}
+	return nil
+}
+
+// findRouter returns the innermost router serving request
+// matching the condition or nil
+func findRouter(req *http.Request, cond func(*Router) bool) *Router {
+	c, _ := req.Context().Value(routerKey).(*routerChain)
+	for ; c != nil; c = c.parent {
+		if cond(c.router) {
+			return c.router
+		}
+	}
+	return nil
+}
+
+// redirect serves request for the canonical path p according to the policy.
+// It returns request to continue serving or nil if response is sent.
+// Location of mounted router is prefixed with its mount path.
+func redirect(w http.ResponseWriter, req *http.Request, p string, policy RedirectPolicy) *http.Request {
+	url := *req.URL
+	url.Path, url.RawPath = p, ""
+
+	switch policy {
+	case Redirect301, Redirect308:
+		code := http.StatusMovedPermanently
+		if policy == Redirect308 {
+			code = http.StatusPermanentRedirect
+		}
+		url.Path = MountPath(req) + p
+		w.Header().Set("Location", url.String())
+		w.WriteHeader(code)
+		return nil
+	case RedirectRewrite:
+		req = req.WithContext(req.Context())
+		req.URL = &url
+	}
+	return req
+}
+
+// toggleSlash adds trailing slash to the path or removes it
+func toggleSlash(p string) string {
+	if p == "/" {
+		return ""
+	}
+	if strings.HasSuffix(p, "/") {
+		return p[:len(p)-1]
+	}
+	return p + "/"
 }
 
 func (r *Router) PathPrefix(s string) *Route {
 	return r.NewRoute(s)
 }
 
+// Use appends standard net/http middleware wrapping every request
+// served by the router, including not found responses and redirects.
+// Middleware added first is the outermost one.
+// ex:
+//    r.Use(loggingHandler, gzipHandler)
+func (r *Router) Use(mw ...func(http.Handler) http.Handler) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.middleware = append(r.middleware, mw...)
+	var h http.Handler = http.HandlerFunc(r.dispatch)
+	for i := len(r.middleware) - 1; i >= 0; i-- {
+		h = r.middleware[i](h)
+	}
+
+	t := r.load().clone()
+	t.handler = h
+	r.table.Store(t)
+}
+
 // ServeHTTP dispatches the handler registered in the matched route.
+// Panics are recovered with JSON 500 response, see Router.OnPanic.
 func (r *Router) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if rw, rreq := recovering(w, req); rw != nil {
+		defer recoverPanic(r, rw, rreq)
+		w, req = rw, rreq
+	}
+	if h := r.load().handler; h != nil {
+		h.ServeHTTP(w, req)
+		return
+	}
+	r.dispatch(w, req)
+}
+
+func (r *Router) dispatch(w http.ResponseWriter, req *http.Request) {
+	req = withRouter(req, r)
+	dispatching(req)
 	if p := cleanPath(req.URL.Path); p != req.URL.Path {
-		url := *req.URL
-		url.Path = p
-		p = url.String()
+		if req = redirect(w, req, p, r.CleanPath); req == nil {
+			return
+		}
+	}
 
-		w.Header().Set("Location", p)
-		w.WriteHeader(http.StatusMovedPermanently)
+	for _, h := range r.load().hosts {
+		if params, ok := h.match(req); ok {
+			if params != nil {
+				req = req.WithContext(context.WithValue(req.Context(), hostKey, params))
+			}
+			h.router.ServeHTTP(w, req)
+			return
+		}
+	}
+
+	rts, vals := r.lookup(req.URL.Path)
+	if len(rts) == 0 && r.TrailingSlash != RedirectOff {
+		if p := toggleSlash(req.URL.Path); p != "" {
+			if alt, _ := r.lookup(p); len(alt) > 0 {
+				if req = redirect(w, req, p, r.TrailingSlash); req == nil {
+					return
+				}
+				rts, vals = r.lookup(req.URL.Path)
+			}
+		}
+	}
+
+	if len(rts) == 0 {
+		notFound(w, req)
 		return
 	}
 
-	r.match(req.URL.Path).ServeHTTP(w, req)
+	rt := routeFor(rts, req.Method)
+	if rt == nil {
+		w.Header().Set("Allow", allowHeader(allowedMethods(rts)))
+		if req.Method == "OPTIONS" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		methodNotAllowed(w, req)
+		return
+	}
+
+	if params := rt.pathParams(vals); params != nil {
+		req = req.WithContext(context.WithValue(req.Context(), paramsKey, params))
+	}
+	if req.Method == "HEAD" {
+		w = headResponseWriter{w}
+	}
+	rt.handler.ServeHTTP(w, req)
 }
 
-var meths = []string{"GET", "POST", "DELETE"}
+type contextKey int
+
+const (
+	paramsKey contextKey = iota
+	routerKey
+	hostKey
+	mountKey
+	singularKey
+	recoverKey
+)
+
+// PathParam returns named parameter captured from the request URL path
+// by the route pattern. ex:
+//    r.HandleFunc("/users/:user_id", func(w http.ResponseWriter, req *http.Request) {
+//        id := rapi.PathParam(req, "user_id")
+//    })
+func PathParam(req *http.Request, name string) string {
+	params, _ := req.Context().Value(paramsKey).(map[string]string)
+	return params[name]
+}
+
+// routeFor returns route serving the HTTP method.
+// Route without methods serves any method, GET route serves HEAD as well.
+func routeFor(rts []*Route, method string) *Route {
+	for _, rt := range rts {
+		if len(rt.methods) == 0 || containsString(rt.methods, method) {
+			return rt
+		}
+	}
+	if method == "HEAD" {
+		return routeFor(rts, "GET")
+	}
+	return nil
+}
+
+// allowedMethods returns list of methods served by routes
+func allowedMethods(rts []*Route) []string {
+	res := []string{}
+	for _, rt := range rts {
+		for _, m := range rt.methods {
+			if !containsString(res, m) {
+				res = append(res, m)
+			}
+		}
+	}
+	if containsString(res, "GET") && !containsString(res, "HEAD") {
+		res = append(res, "HEAD")
+	}
+	return res
+}
+
+// allowHeader returns value of Allow header for the methods
+func allowHeader(methods []string) string {
+	res := append([]string{"OPTIONS"}, methods...)
+	sort.Strings(res)
+	return strings.Join(res, ", ")
+}
+
+func sameMethods(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, v := range a {
+		if !containsString(b, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// HostParam returns named parameter captured from the request host
+// by the sub-router host pattern. See Router.Host.
+func HostParam(req *http.Request, name string) string {
+	params, _ := req.Context().Value(hostKey).(map[string]string)
+	return params[name]
+}
+
+var meths = []string{"GET", "POST", "PUT", "PATCH", "DELETE"}
 
 // implements extracting custom methods from controller
-// custom method names should begin from GET, POST or DELETE
+// custom method names should begin from GET, POST, PUT, PATCH or DELETE.
+// Optional Patch action is extracted as well.
 func implements(v interface{}) []string {
 	res := []string{}
 	t := reflect.TypeOf(v)
 	for i := 0; i < t.NumMethod(); i++ {
 		m := t.Method(i)
+		if m.Name == "Patch" {
+			res = append(res, m.Name)
+			continue
+		}
 		for _, v := range meths {
 			if strings.HasPrefix(m.Name, v) {
 				res = append(res, m.Name)
@@ -3,21 +3,48 @@ package rapi
 import (
 	"fmt"
 	"net/http"
+	"sort"
+	"strings"
 	"testing"
 )
 
 func HandlerForTest(w http.ResponseWriter, req *http.Request)  {}
 func HandlerForTest1(w http.ResponseWriter, req *http.Request) {}
 
+// matched returns prefix of the route matching the path
+func matched(r *Router, path string) string {
+	if rts, _ := r.lookup(path); len(rts) > 0 {
+		return rts[0].prefix
+	}
+	return ""
+}
+
+// lookupParams returns path parameters captured by route matching the path
+func lookupParams(r *Router, path string) map[string]string {
+	rts, vals := r.lookup(path)
+	return rts[0].pathParams(vals)
+}
+
+// countRoutes returns number of exact or prefix routes
+func countRoutes(r *Router, exact bool) int {
+	n := 0
+	for _, v := range r.load().routes {
+		if v.exact == exact {
+			n++
+		}
+	}
+	return n
+}
+
 func TestHandleFunc(t *testing.T) {
 	r := NewRouter()
 
 	r.HandleFunc("/pages", HandlerForTest)
-	assertEqual(t, 0, len(r.routes))
-	assertEqual(t, 1, len(r.namedRoutes))
+	assertEqual(t, 0, countRoutes(r, false))
+	assertEqual(t, 1, countRoutes(r, true))
 
-	assertEqual(t, fmt.Sprint(http.NotFoundHandler()), fmt.Sprint(r.match("/pages/1")))
-	assertEqual(t, fmt.Sprint(HandlerForTest), fmt.Sprint(r.match("/pages")))
+	assertEqual(t, "", matched(r, "/pages/1"))
+	assertEqual(t, "/pages", matched(r, "/pages"))
 }
 
 func TestPathPrefix(t *testing.T) {
@@ -25,14 +52,14 @@ func TestPathPrefix(t *testing.T) {
 	p := r.PathPrefix("/api")
 	p.HandleFunc("//pages2", HandlerForTest)
 	p.HandleFunc("/pages1", HandlerForTest1)
-	assertEqual(t, 2, len(r.routes))
-	assertEqual(t, 0, len(r.namedRoutes))
+	assertEqual(t, 2, countRoutes(r, false))
+	assertEqual(t, 0, countRoutes(r, true))
 
-	assertEqual(t, fmt.Sprint(http.NotFoundHandler()), fmt.Sprint(r.match("/pages1/1")))
-	assertEqual(t, fmt.Sprint(HandlerForTest1), fmt.Sprint(r.match("/api/pages1/1")))
+	assertEqual(t, "", matched(r, "/pages1/1"))
+	assertEqual(t, "/api/pages1", matched(r, "/api/pages1/1"))
 
-	assertEqual(t, fmt.Sprint(http.NotFoundHandler()), fmt.Sprint(r.match("/pages2/1")))
-	assertEqual(t, fmt.Sprint(HandlerForTest), fmt.Sprint(r.match("/api/pages2/1")))
+	assertEqual(t, "", matched(r, "/pages2/1"))
+	assertEqual(t, "/api/pages2", matched(r, "/api/pages2/1"))
 }
 
 func TestRoute(t *testing.T) {
@@ -44,20 +71,20 @@ func TestRoute(t *testing.T) {
 	c := &C{}
 
 	r.Route("/pages", c, "c")
-	assertEqual(t, 1, len(r.routes))
-	assertEqual(t, 0, len(r.namedRoutes))
+	assertEqual(t, 1, countRoutes(r, false))
+	assertEqual(t, 0, countRoutes(r, true))
 
-	assertEqual(t, "", r.matchNamed("/pages/1"))
-	assertEqual(t, "/pages", r.matchCommon("/pages/1"))
+	assertEqual(t, "/pages", matched(r, "/pages/1"))
 
 	p := r.PathPrefix("/api")
 	p.Route("/pages", c, "c")
-	assertEqual(t, 2, len(r.routes))
-	assertEqual(t, 0, len(r.namedRoutes))
+	assertEqual(t, 2, countRoutes(r, false))
+	assertEqual(t, 0, countRoutes(r, true))
 
-	assertEqual(t, "", r.matchNamed("/api/pages/2"))
-	assertEqual(t, "/api/pages", r.matchCommon("/api/pages/2"))
+	assertEqual(t, "/api/pages", matched(r, "/api/pages/2"))
 
+	r.Route("/pages", c, "c")
+	assertEqual(t, 2, countRoutes(r, false))
 }
 
 type CT struct {
@@ -78,11 +105,518 @@ func TestRoutesOrder(t *testing.T) {
 	r.Route("/aaaaa", &CT{}, "c")
 	r.Route("/a/a", &CT{}, "c")
 
-	assertEqual(t, "/aa", r.matchCommon("/aa/1"))
-	assertEqual(t, "/a", r.matchNamed("/a"))
-	assertEqual(t, "/aaa", r.matchCommon("/aaa/"))
-	assertEqual(t, "/aaaa", r.matchCommon("/aaaa/22"))
-	assertEqual(t, "/a/a", r.matchCommon("/a/a/"))
+	assertEqual(t, "/aa", matched(r, "/aa/1"))
+	assertEqual(t, "/a", matched(r, "/a"))
+	assertEqual(t, "/aaa", matched(r, "/aaa/"))
+	assertEqual(t, "/aaaa", matched(r, "/aaaa/22"))
+	assertEqual(t, "/a/a", matched(r, "/a/a/"))
+	assertEqual(t, "", matched(r, "/a/b"))
+	assertEqual(t, "", matched(r, "/aab"))
+}
+
+func TestPatternRoutes(t *testing.T) {
+	r := NewRouter()
+	r.HandleFunc("/users/:user_id", HandlerForTest)
+	r.Route("/users/:user_id/posts", &CT{}, "post")
+	r.Route("/users/me/posts", &CT{}, "post")
+
+	assertEqual(t, "/users/:user_id", matched(r, "/users/10"))
+	assertEqual(t, "", matched(r, "/users/10/comments"))
+	assertEqual(t, "", matched(r, "/users/"))
+	assertEqual(t, "/users/:user_id/posts", matched(r, "/users/10/posts/1"))
+	assertEqual(t, "/users/me/posts", matched(r, "/users/me/posts/1"))
+	assertEqual(t, "/users/:user_id", matched(r, "/users/me"))
+	assertEqual(t, "", matched(r, "/users/10/postsx"))
+
+	var id string
+	r.HandleFunc("/pages/:page_id", func(w http.ResponseWriter, req *http.Request) {
+		id = PathParam(req, "page_id")
+	})
+	r.ServeHTTP(newRecorder(), newRequest("GET", "http://localhost/pages/22", ""))
+	assertEqual(t, "22", id)
+}
+
+func TestParamNames(t *testing.T) {
+	r := NewRouter()
+	r.HandleFunc("/users/:id", HandlerForTest)
+	r.HandleFunc("/users/:user_id/posts/:id", HandlerForTest)
+	r.HandleFunc("/files/*path", HandlerForTest)
+
+	params := lookupParams(r, "/users/1")
+	assertEqual(t, "1", params["id"])
+
+	params = lookupParams(r, "/users/1/posts/2")
+	assertEqual(t, "1", params["user_id"])
+	assertEqual(t, "2", params["id"])
+
+	params = lookupParams(r, "/files/css/app.css")
+	assertEqual(t, "css/app.css", params["path"])
+}
+
+func TestMatchPattern(t *testing.T) {
+	params, rest, ok := matchPattern("/users/:user_id/posts", "/users/1/posts/2/edit")
+	assertEqual(t, true, ok)
+	assertEqual(t, "/2/edit", rest)
+	assertEqual(t, "1", params["user_id"])
+
+	_, _, ok = matchPattern("/pages", "/pagesx")
+	assertEqual(t, false, ok)
+
+	_, rest, ok = matchPattern("/images/", "/images/a.png")
+	assertEqual(t, true, ok)
+	assertEqual(t, "a.png", rest)
+}
+
+func TestMethods(t *testing.T) {
+	r := NewRouter()
+	r.HandleFunc("/login", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("get"))
+	}, "GET")
+	r.HandleFunc("/login", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("post"))
+	}, "post")
+
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/login", ""))
+	assertEqual(t, "get", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("POST", "http://localhost/login", ""))
+	assertEqual(t, "post", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("DELETE", "http://localhost/login", ""))
+	assertEqual(t, http.StatusMethodNotAllowed, rec.Code)
+	assertEqual(t, "GET, HEAD, OPTIONS, POST", rec.Header().Get("Allow"))
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("HEAD", "http://localhost/login", ""))
+	assertEqual(t, http.StatusOK, rec.Code)
+	assertEqual(t, "", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("OPTIONS", "http://localhost/login", ""))
+	assertEqual(t, http.StatusNoContent, rec.Code)
+	assertEqual(t, "GET, HEAD, OPTIONS, POST", rec.Header().Get("Allow"))
+}
+
+type ReadC struct {
+	Request
+}
+
+func (c *ReadC) Index() {}
+
+func (c *ReadC) Create() {}
+
+func (c *ReadC) Show() {}
+
+func TestControllerMethods(t *testing.T) {
+	r := NewRouter()
+	r.Route("/pages", &ReadC{}, "page")
+
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("DELETE", "http://localhost/pages", ""))
+	assertEqual(t, http.StatusMethodNotAllowed, rec.Code)
+	assertEqual(t, "GET, HEAD, OPTIONS, POST", rec.Header().Get("Allow"))
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("OPTIONS", "http://localhost/pages/1", ""))
+	assertEqual(t, http.StatusNoContent, rec.Code)
+	assertEqual(t, "GET, HEAD, OPTIONS", rec.Header().Get("Allow"))
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("PATCH", "http://localhost/pages/1", ""))
+	assertEqual(t, http.StatusMethodNotAllowed, rec.Code)
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages/1/unknown", ""))
+	assertEqual(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestURL(t *testing.T) {
+	r := NewRouter()
+	r.Route("/users/:user_id/posts", &TestC{}, "post").Name("posts")
+	r.HandleFunc("/files/*path", HandlerForTest).Name("files")
+	r.HandleFunc("/about", HandlerForTest).Name("about")
+
+	u, err := r.URL("posts", "user_id", "1")
+	assertEqual(t, nil, err)
+	assertEqual(t, "/users/1/posts", u)
+
+	u, err = r.URL("posts", "user_id", "1", "id", "2", "action", "publish")
+	assertEqual(t, nil, err)
+	assertEqual(t, "/users/1/posts/2/publish", u)
+
+	u, _ = r.URL("posts", "user_id", "1", "action", "collection")
+	assertEqual(t, "/users/1/posts/collection", u)
+
+	u, _ = r.URL("files", "path", "css/app.css")
+	assertEqual(t, "/files/css/app.css", u)
+
+	u, _ = r.URL("about")
+	assertEqual(t, "/about", u)
+
+	_, err = r.URL("posts", "id", "2")
+	assertNotEqual(t, nil, err)
+
+	_, err = r.URL("posts", "user_id", "1", "action", "unknown")
+	assertNotEqual(t, nil, err)
+
+	_, err = r.URL("posts", "user_id")
+	assertNotEqual(t, nil, err)
+
+	_, err = r.URL("unknown")
+	assertNotEqual(t, nil, err)
+
+	r.Resource("/users", &TestC{}).Name("users").Resource("/posts", &TestC{}).Name("user_posts")
+	u, err = r.URL("users", "id", "1")
+	assertEqual(t, nil, err)
+	assertEqual(t, "/users/1", u)
+	u, _ = r.URL("user_posts", "user_id", "1", "id", "2")
+	assertEqual(t, "/users/1/posts/2", u)
+	for _, v := range r.Routes() {
+		if v.Prefix == "/users" {
+			assertEqual(t, "users", v.Name)
+		}
+	}
+
+	r.Route("/pages", &LinkC{}, "page")
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages", ""))
+	assertEqual(t, "/users/5/posts/7", rec.Header().Get("Location"))
+}
+
+type LinkC struct {
+	Request
+}
+
+func (c *LinkC) Index() {
+	u, _ := c.URLFor("posts", "user_id", "5", "id", "7")
+	c.w.Header().Set("Location", u)
+}
+
+func TestUse(t *testing.T) {
+	calls := []string{}
+	trace := func(s string) ReqFunc {
+		return func(c Controller) bool {
+			calls = append(calls, s)
+			if c.QueryParam("deny") == s {
+				c.RenderJSONError(http.StatusUnauthorized, "unauthorized")
+				return false
+			}
+			return true
+		}
+	}
+
+	r := NewRouter()
+	api := r.PathPrefix("/api").Use(trace("a"))
+	v1 := api.NewRoute("/v1").Use(trace("b"))
+	v1.Route("/pages", &TestC{}, "page", trace("c"))
+	v1.HandleFunc("/stats", HandlerForTest1)
+	api.NewRoute("/files/").FileServer("./")
+	r.HandleFunc("/open", HandlerForTest)
+
+	r.ServeHTTP(newRecorder(), newRequest("GET", "http://localhost/api/v1/pages", ""))
+	assertEqual(t, "a,b,c", strings.Join(calls, ","))
+
+	calls = calls[:0]
+	r.ServeHTTP(newRecorder(), newRequest("GET", "http://localhost/api/v1/stats", ""))
+	assertEqual(t, "a,b", strings.Join(calls, ","))
+
+	calls = calls[:0]
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/api/files/x?deny=a", ""))
+	assertEqual(t, "a", strings.Join(calls, ","))
+	assertEqual(t, http.StatusUnauthorized, rec.Code)
+
+	calls = calls[:0]
+	r.ServeHTTP(newRecorder(), newRequest("GET", "http://localhost/open", ""))
+	assertEqual(t, 0, len(calls))
+}
+
+func TestRouterUse(t *testing.T) {
+	mw := func(s string) func(http.Handler) http.Handler {
+		return func(h http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Add("X-Trace", s)
+				h.ServeHTTP(w, req)
+			})
+		}
+	}
+
+	r := NewRouter()
+	r.Use(mw("a"))
+	r.Use(mw("b"))
+	r.HandleFunc("/pages", HandlerForTest)
+	r.Route("/users", &TestC{}, "user")
+	r.PathPrefix("/files/").FileServer("./")
+
+	for _, v := range []string{"/pages", "/users/1", "/files/x", "/unknown", "/pages/../pages"} {
+		rec := newRecorder()
+		r.ServeHTTP(rec, newRequest("GET", "http://localhost"+v, ""))
+		assertEqual(t, "a,b", strings.Join(rec.Header()["X-Trace"], ","))
+	}
+}
+
+func TestNotFound(t *testing.T) {
+	r := NewRouter()
+	r.HandleFunc("/pages", HandlerForTest, "GET")
+
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/unknown", ""))
+	assertEqual(t, http.StatusNotFound, rec.Code)
+	assertEqual(t, "{\"errors\":{\"message\":[\"not found\"]}}\n", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("POST", "http://localhost/pages", ""))
+	assertEqual(t, http.StatusMethodNotAllowed, rec.Code)
+	assertEqual(t, "{\"errors\":{\"message\":[\"method not allowed\"]}}\n", rec.Body.String())
+
+	r.NotFound = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	})
+	r.MethodNotAllowed = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusConflict)
+	})
+	r.Route("/users", &ReadC{}, "user")
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/unknown", ""))
+	assertEqual(t, http.StatusTeapot, rec.Code)
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("POST", "http://localhost/pages", ""))
+	assertEqual(t, http.StatusConflict, rec.Code)
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("DELETE", "http://localhost/users/1", ""))
+	assertEqual(t, http.StatusConflict, rec.Code)
+	assertEqual(t, "GET, HEAD, OPTIONS", rec.Header().Get("Allow"))
+}
+
+func TestRedirectPolicy(t *testing.T) {
+	r := NewRouter()
+	r.HandleFunc("/pages", func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte(req.URL.Path))
+	})
+
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("POST", "http://localhost/a/../pages?q=1", ""))
+	assertEqual(t, http.StatusMovedPermanently, rec.Code)
+	assertEqual(t, "http://localhost/pages?q=1", rec.Header().Get("Location"))
+
+	r.CleanPath = Redirect308
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("POST", "http://localhost/a/../pages", ""))
+	assertEqual(t, http.StatusPermanentRedirect, rec.Code)
+
+	r.CleanPath = RedirectRewrite
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("POST", "http://localhost//pages", ""))
+	assertEqual(t, "/pages", rec.Body.String())
+
+	r.CleanPath = RedirectOff
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("POST", "http://localhost//pages", ""))
+	assertEqual(t, http.StatusNotFound, rec.Code)
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages/", ""))
+	assertEqual(t, http.StatusNotFound, rec.Code)
+
+	r.TrailingSlash = Redirect308
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages/", ""))
+	assertEqual(t, http.StatusPermanentRedirect, rec.Code)
+	assertEqual(t, "http://localhost/pages", rec.Header().Get("Location"))
+
+	r.TrailingSlash = RedirectRewrite
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages/", ""))
+	assertEqual(t, "/pages", rec.Body.String())
+}
+
+type PostsC struct {
+	Request
+}
+
+func (c *PostsC) Index() {
+	c.RenderJSON(200, JSONData{"user": c.PathParam("user_id")})
+}
+
+func (c *PostsC) Show() {
+	c.RenderJSON(200, JSONData{"user": c.PathParam("user_id"), c.Root: c.URL.ID})
+}
+
+type ProfileC struct {
+	Request
+}
+
+func (c *ProfileC) Show() {
+	c.RenderJSON(200, JSONData{c.Root: "show"})
+}
+
+func (c *ProfileC) Create() {
+	c.RenderJSON(200, JSONData{c.Root: "create"})
+}
+
+func (c *ProfileC) Destroy() {
+	c.RenderJSON(200, JSONData{c.Root: "destroy"})
+}
+
+func (c *ProfileC) GETAvatar() {
+	c.RenderJSON(200, JSONData{c.Root: "avatar"})
+}
+
+func TestResource(t *testing.T) {
+	r := NewRouter()
+	users := r.PathPrefix("/api").Resource("/users", &TestC{})
+	users.Resource("/posts", &PostsC{})
+	r.SingularResource("/profile", &ProfileC{})
+
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/api/users", ""))
+	assertEqual(t, "{\"user\":\"index\"}\n", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/api/users/1/posts", ""))
+	assertEqual(t, "{\"user\":\"1\"}\n", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/api/users/1/posts/2", ""))
+	assertEqual(t, "{\"post\":\"2\",\"user\":\"1\"}\n", rec.Body.String())
+
+	for _, v := range [][]string{
+		{"GET", "/profile", "show"},
+		{"POST", "/profile", "create"},
+		{"DELETE", "/profile/", "destroy"},
+		{"GET", "/profile/avatar", "avatar"},
+	} {
+		rec = newRecorder()
+		r.ServeHTTP(rec, newRequest(v[0], "http://localhost"+v[1], ""))
+		assertEqual(t, "{\"profile\":\""+v[2]+"\"}\n", rec.Body.String())
+	}
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("PUT", "http://localhost/profile", ""))
+	assertEqual(t, http.StatusMethodNotAllowed, rec.Code)
+	assertEqual(t, "DELETE, GET, HEAD, OPTIONS, POST", rec.Header().Get("Allow"))
+}
+
+func TestOnlyExcept(t *testing.T) {
+	r := NewRouter()
+	r.Route("/pages", &TestC{}, "page").Only("Index", "Show")
+	r.Route("/admin/pages", &TestC{}, "page").Except("Create")
+	r.Resource("/users", &TestC{}).Only("Show").Resource("/posts", &PostsC{})
+
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages/1", ""))
+	assertEqual(t, http.StatusOK, rec.Code)
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("POST", "http://localhost/pages", "{}"))
+	assertEqual(t, http.StatusMethodNotAllowed, rec.Code)
+	assertEqual(t, "GET, HEAD, OPTIONS", rec.Header().Get("Allow"))
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages/collection", ""))
+	assertEqual(t, "{\"page\":\"show\"}\n", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("POST", "http://localhost/admin/pages", "{}"))
+	assertEqual(t, http.StatusMethodNotAllowed, rec.Code)
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/admin/pages/collection", ""))
+	assertEqual(t, "{\"page\":\"collection\"}\n", rec.Body.String())
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/users", ""))
+	assertEqual(t, http.StatusBadRequest, rec.Code)
+
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/users/1/posts", ""))
+	assertEqual(t, http.StatusOK, rec.Code)
+
+	assertEqual(t, nil, r.Validate())
+	r.Route("/other", &TestC{}, "page").Only("Unknown")
+	r.HandleFunc("/about", HandlerForTest).Except("Index")
+	assertNotEqual(t, nil, r.Validate())
+}
+
+func TestSingularize(t *testing.T) {
+	assertEqual(t, "user", singularize("users"))
+	assertEqual(t, "category", singularize("categories"))
+	assertEqual(t, "box", singularize("boxes"))
+	assertEqual(t, "address", singularize("addresses"))
+	assertEqual(t, "status", singularize("status"))
+	assertEqual(t, "staff", singularize("staff"))
+}
+
+// linearRouter is the prefix scan over reverse sorted keys
+// used by the router before the tree, kept for benchmarks comparison
+type linearRouter struct {
+	routes map[string]http.Handler
+	keys   []string
+}
+
+func (r *linearRouter) add(prefix string, h http.Handler) {
+	r.routes[prefix] = h
+	r.keys = r.keys[:0]
+	for k := range r.routes {
+		r.keys = append(r.keys, k)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(r.keys)))
+}
+
+func (r *linearRouter) match(path string) http.Handler {
+	for _, v := range r.keys {
+		if strings.HasPrefix(path, v) {
+			return r.routes[v]
+		}
+	}
+	return http.NotFoundHandler()
+}
+
+func manyRoutes() []string {
+	res := []string{}
+	for i := 0; i < 300; i++ {
+		res = append(res, fmt.Sprintf("/api/v%d/resource%d", i%3, i))
+	}
+	return res
+}
+
+func BenchmarkMatchManyRoutes(b *testing.B) {
+	r := NewRouter()
+	for _, v := range manyRoutes() {
+		r.HandlePrefix(v, http.HandlerFunc(HandlerForTest))
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		r.lookup("/api/v0/resource150/10")
+	}
+}
+
+func BenchmarkLinearMatchManyRoutes(b *testing.B) {
+	r := &linearRouter{routes: make(map[string]http.Handler)}
+	for _, v := range manyRoutes() {
+		r.add(v, http.HandlerFunc(HandlerForTest))
+	}
+
+	b.ResetTimer()
+	for n := 0; n < b.N; n++ {
+		r.match("/api/v0/resource150/10")
+	}
+}
+
+func BenchmarkMatchParams(b *testing.B) {
+	r := NewRouter()
+	r.Route("/users/:user_id/posts", &CT{}, "post")
+
+	for n := 0; n < b.N; n++ {
+		r.lookup("/users/10/posts/1")
+	}
 }
 
 func BenchmarkMatch(b *testing.B) {
@@ -96,6 +630,49 @@ func BenchmarkMatch(b *testing.B) {
 	p.HandleFunc("/pages6", HandlerForTest)
 
 	for n := 0; n < b.N; n++ {
-		r.match("/api/pages1/1")
+		r.lookup("/api/pages1/1")
 	}
 }
+
+func TestOnlyConcurrent(t *testing.T) {
+	r := NewRouter()
+	rt := r.Route("/pages", &TestC{}, "page")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			r.ServeHTTP(newRecorder(), newRequest("GET", "http://localhost/pages/1", ""))
+			r.Routes()
+		}
+	}()
+	rt.Only("Index", "Show").Name("pages")
+	<-done
+
+	routes := r.Routes()
+	assertEqual(t, "Index,Show", strings.Join(routes[0].Actions, ","))
+	assertEqual(t, "pages", routes[0].Name)
+}
+
+func TestOnlyExceptCombined(t *testing.T) {
+	r := NewRouter()
+	rt := r.Route("/pages", &TestC{}, "page")
+	rt.Only("Index", "Show")
+	rt.Except("Show")
+	users := r.Resource("/users", &TestC{})
+	users.Only("Index", "Show")
+	users.Except("Index")
+
+	assertEqual(t, "Index", strings.Join(r.Routes()[0].Actions, ","))
+	assertEqual(t, "Show", strings.Join(r.Routes()[1].Actions, ","))
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages/1", ""))
+	assertNotEqual(t, http.StatusOK, rec.Code)
+	assertEqual(t, nil, r.Validate())
+
+	r.Remove("/pages")
+	rt.Only("Index")
+	err := r.Validate()
+	assertNotEqual(t, nil, err)
+	assertEqual(t, "rapi: route /pages is not registered to restrict actions", err.Error())
+}
@@ -0,0 +1,100 @@
+package rapi
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// RouteKind is the kind of registered route
+type RouteKind string
+
+// Route kinds
+const (
+	KindController RouteKind = "controller"
+	KindHandler    RouteKind = "handler"
+	KindFileServer RouteKind = "file server"
+	KindMount      RouteKind = "mount"
+)
+
+// RouteInfo describes registered route
+type RouteInfo struct {
+	Host       string    `json:"host,omitempty"`
+	Prefix     string    `json:"prefix"`
+	Name       string    `json:"name,omitempty"`
+	Kind       RouteKind `json:"kind"`
+	Exact      bool      `json:"exact"`
+	Methods    []string  `json:"methods,omitempty"`
+	Controller string    `json:"controller,omitempty"`
+	Actions    []string  `json:"actions,omitempty"`
+	Middleware int       `json:"middleware"`
+}
+
+// Routes returns registered routes including routes of host sub-routers
+// and API versions sorted by host and prefix.
+func (r *Router) Routes() []RouteInfo {
+	t := r.load()
+	names := make(map[*Route]string, len(t.names))
+	for k, v := range t.names {
+		names[v] = k
+	}
+
+	res := []RouteInfo{}
+	for _, rt := range t.routes {
+		info := RouteInfo{
+			Prefix:     rt.prefix,
+			Name:       names[rt],
+			Kind:       rt.kind,
+			Exact:      rt.exact,
+			Methods:    rt.methods,
+			Middleware: len(rt.funcs),
+		}
+		if rt.ctrl != nil {
+			info.Controller = "*" + rt.ctrl.typ.String()
+			info.Actions = rt.ctrl.actionNames()
+		}
+		res = append(res, info)
+
+		if rt.versions == nil {
+			continue
+		}
+		for _, ver := range rt.versions.list() {
+			prefix := strings.TrimSuffix(rt.prefix, "/") + "/v" + strconv.Itoa(ver.number)
+			for _, v := range ver.router.Routes() {
+				v.Prefix = prefix + v.Prefix
+				res = append(res, v)
+			}
+		}
+	}
+
+	for _, h := range t.hosts {
+		host := strings.Join(h.labels, ".")
+		if h.scheme != "" {
+			host = h.scheme + "://" + host
+		}
+		for _, v := range h.router.Routes() {
+			if v.Host == "" {
+				v.Host = host
+			}
+			res = append(res, v)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Host != res[j].Host {
+			return res[i].Host < res[j].Host
+		}
+		return res[i].Prefix < res[j].Prefix
+	})
+	return res
+}
+
+// RoutesHandler returns handler rendering registered routes in JSON format.
+// ex:
+//    r.HandleFunc("/debug/routes", r.RoutesHandler().ServeHTTP, "GET")
+func (r *Router) RoutesHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		RenderJSON(w, http.StatusOK, JSONData{"routes": r.Routes()})
+	})
+}
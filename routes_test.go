@@ -0,0 +1,71 @@
+package rapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestRoutes(t *testing.T) {
+	auth := func(c Controller) bool { return true }
+
+	r := NewRouter()
+	api := r.PathPrefix("/api").Use(auth)
+	api.Route("/pages", &TestC{}, "page", auth).Name("pages")
+	r.HandleFunc("/login", HandlerForTest, "POST")
+	r.PathPrefix("/images/").FileServer("./")
+	r.Host("admin.example.com").HandleFunc("/", HandlerForTest)
+
+	routes := r.Routes()
+	assertEqual(t, 4, len(routes))
+
+	assertEqual(t, "/api/pages", routes[0].Prefix)
+	assertEqual(t, "pages", routes[0].Name)
+	assertEqual(t, KindController, routes[0].Kind)
+	assertEqual(t, "*rapi.TestC", routes[0].Controller)
+	assertEqual(t, "Create,GETCollection,Index,PUTPublish,Patch,Show", strings.Join(routes[0].Actions, ","))
+	assertEqual(t, 2, routes[0].Middleware)
+
+	assertEqual(t, "/images/", routes[1].Prefix)
+	assertEqual(t, KindFileServer, routes[1].Kind)
+
+	assertEqual(t, "/login", routes[2].Prefix)
+	assertEqual(t, KindHandler, routes[2].Kind)
+	assertEqual(t, true, routes[2].Exact)
+	assertEqual(t, "POST", strings.Join(routes[2].Methods, ","))
+
+	assertEqual(t, "admin.example.com", routes[3].Host)
+
+	r.HandleFunc("/debug/routes", r.RoutesHandler().ServeHTTP, "GET")
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/debug/routes", ""))
+	assertEqual(t, http.StatusOK, rec.Code)
+
+	var res struct{ Routes []RouteInfo }
+	json.NewDecoder(rec.Body).Decode(&res)
+	assertEqual(t, 5, len(res.Routes))
+	assertEqual(t, "/debug/routes", res.Routes[1].Prefix)
+}
+
+func TestRoutesConcurrentName(t *testing.T) {
+	r := NewRouter()
+	rt := r.HandleFunc("/about", HandlerForTest)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			r.Routes()
+		}
+	}()
+	rt.Name("info")
+	rt.Name("about")
+	<-done
+
+	assertEqual(t, "about", r.Routes()[0].Name)
+	_, err := r.URL("info")
+	assertNotEqual(t, nil, err)
+	u, _ := r.URL("about")
+	assertEqual(t, "/about", u)
+}
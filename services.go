@@ -0,0 +1,79 @@
+package rapi
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// services is the container of values injected into controllers
+type services struct {
+	mu     sync.RWMutex
+	values map[reflect.Type]reflect.Value
+}
+
+// Provide registers services injected into controller actions declaring
+// arguments of the service type or of the interface implemented by the service
+// and into exported controller fields of such type tagged inject.
+// Services must be provided before registering routes using them.
+// Host and version sub-routers share services of the router.
+// ex:
+//    r.Provide(db, logger)
+//    r.Route("/pages", &Pages{}, "page")
+//
+//    type Pages struct {
+//        rapi.Request
+//        Log *log.Logger `inject:""`
+//    }
+//
+//    func (p *Pages) Index(db *sql.DB) {
+//        // do some work here
+//    }
+func (r *Router) Provide(vals ...interface{}) {
+	for _, v := range vals {
+		r.services.set(reflect.TypeOf(v), reflect.ValueOf(v))
+	}
+}
+
+// ProvideAs registers service under type T, which may be interface type
+// implemented by several provided services. See Router.Provide.
+//    rapi.ProvideAs[Logger](r, logger)
+func ProvideAs[T any](r *Router, v T) {
+	r.services.set(reflect.TypeOf((*T)(nil)).Elem(), reflect.ValueOf(&v).Elem())
+}
+
+func (s *services) set(t reflect.Type, v reflect.Value) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.values == nil {
+		s.values = make(map[reflect.Type]reflect.Value)
+	}
+	s.values[t] = v
+}
+
+// lookup returns service of the type or the only service implementing
+// the interface type. ok is false if there is no such service.
+func (s *services) lookup(t reflect.Type) (v reflect.Value, ok bool, err error) {
+	if s == nil {
+		return v, false, nil
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if v, ok := s.values[t]; ok {
+		return v, true, nil
+	}
+	if t.Kind() != reflect.Interface {
+		return v, false, nil
+	}
+	for k, val := range s.values {
+		if !k.Implements(t) {
+			continue
+		}
+		if ok {
+			return v, false, fmt.Errorf("several services implement %s", t)
+		}
+		v, ok = val, true
+	}
+	return v, ok, nil
+}
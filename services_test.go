@@ -0,0 +1,104 @@
+package rapi
+
+import (
+	"io"
+	"log"
+	"os"
+	"strings"
+	"testing"
+)
+
+type DIC struct {
+	Request
+	Prefix string
+	Store  *argsStore `inject:""`
+	Namer  argsNamer  `inject:""`
+	count  int
+}
+
+func (c *DIC) Index() {
+	c.count++
+	c.RenderJSON(200, JSONData{c.Root: c.Prefix + c.Store.name + c.Namer.Name(), "count": c.count})
+}
+
+type BadDIC struct {
+	Request
+	Store *argsStore `inject:""`
+	namer argsNamer  `inject:""`
+}
+
+func (c *BadDIC) Index() {}
+
+type otherNamer struct{}
+
+func (otherNamer) Name() string {
+	return "other"
+}
+
+func TestControllerFields(t *testing.T) {
+	r := NewRouter()
+	r.Provide(&argsStore{name: "main"})
+	ProvideAs[argsNamer](r, &argsStore{name: "named"})
+	r.Route("/pages", &DIC{Prefix: "p:", count: 10}, "page")
+	assertEqual(t, nil, r.Validate())
+
+	for i := 0; i < 2; i++ {
+		rec := newRecorder()
+		r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages", ""))
+		assertEqual(t, `{"count":1,"page":"p:mainnamed"}`+"\n", rec.Body.String())
+	}
+}
+
+func TestRouteFunc(t *testing.T) {
+	r := NewRouter()
+	r.Provide(&argsStore{name: "main"}, &argsStore{name: "other"})
+	ProvideAs[argsNamer](r, &argsStore{name: "named"})
+	calls := 0
+	r.RouteFunc("/pages", func() Controller {
+		calls++
+		return &DIC{Prefix: "f:", count: calls}
+	}, "page")
+	assertEqual(t, nil, r.Validate())
+
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages", ""))
+	assertEqual(t, `{"count":3,"page":"f:othernamed"}`+"\n", rec.Body.String())
+	assertEqual(t, 2, calls)
+}
+
+func TestControllerFieldsErrors(t *testing.T) {
+	r := NewRouter()
+	r.Provide(&argsStore{name: "main"}, otherNamer{})
+	r.Route("/pages", &BadDIC{}, "page")
+	r.Route("/users", &DIC{}, "user")
+
+	err := r.Validate()
+	assertNotEqual(t, nil, err)
+	msg := err.Error()
+	for _, v := range []string{
+		"field namer of controller *rapi.BadDIC tagged inject is not exported",
+		"field Namer of controller *rapi.DIC: several services implement rapi.argsNamer",
+	} {
+		assertEqual(t, true, strings.Contains(msg, v))
+	}
+
+	r = NewRouter()
+	r.Route("/pages", &DIC{}, "page")
+	assertEqual(t, true, strings.Contains(r.Validate().Error(), "no service *rapi.argsStore for field Store of controller *rapi.DIC"))
+
+	r = NewRouter()
+	r.Provide(&argsStore{name: "main"})
+	ProvideAs[argsNamer](r, &argsStore{name: "named"})
+	n := 0
+	r.RouteFunc("/pages", func() Controller {
+		if n++; n > 1 {
+			return &TestC{}
+		}
+		return &DIC{}
+	}, "page")
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(os.Stderr)
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages", ""))
+	assertEqual(t, 500, rec.Code)
+}
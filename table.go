@@ -0,0 +1,29 @@
+package rapi
+
+import "net/http"
+
+// table is the immutable routing table. Router builds a new table
+// on every change and swaps it atomically, so requests are served
+// without locking while routes are registered or removed.
+type table struct {
+	routes  []*Route
+	tree    *node
+	names   map[string]*Route
+	hosts   []*hostRouter
+	handler http.Handler // dispatch wrapped with middleware
+}
+
+// clone returns shallow copy of the table
+func (t *table) clone() *table {
+	c := *t
+	return &c
+}
+
+// setRoutes sets routes and rebuilds routes tree
+func (t *table) setRoutes(routes []*Route) {
+	t.routes = routes
+	t.tree = &node{}
+	for _, v := range routes {
+		t.tree.insert(v.prefix, v, v.exact)
+	}
+}
@@ -0,0 +1,99 @@
+package rapi
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"testing"
+)
+
+func TestRemove(t *testing.T) {
+	r := NewRouter()
+	r.Route("/pages", &TestC{}, "page").Name("pages")
+	r.HandleFunc("/about", HandlerForTest)
+
+	assertEqual(t, true, r.Remove("/pages"))
+	assertEqual(t, false, r.Remove("/pages"))
+	assertEqual(t, "", matched(r, "/pages/1"))
+	assertEqual(t, "/about", matched(r, "/about"))
+
+	_, err := r.URL("pages")
+	assertNotEqual(t, nil, err)
+}
+
+func TestConcurrentRegistration(t *testing.T) {
+	r := NewRouter()
+	r.Route("/pages", &TestC{}, "page")
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-stop:
+					return
+				default:
+				}
+				rec := newRecorder()
+				r.ServeHTTP(rec, newRequest("GET", "http://localhost/pages/1", ""))
+				if rec.Code != http.StatusOK {
+					t.Errorf("unexpected status %d", rec.Code)
+					return
+				}
+				r.ServeHTTP(newRecorder(), newRequest("GET", "http://localhost/tenants/t1/pages", ""))
+				r.URL("tenant")
+				r.Routes()
+			}
+		}()
+	}
+
+	for i := 0; i < 200; i++ {
+		prefix := fmt.Sprintf("/tenants/t%d/pages", i%5)
+		r.Route(prefix, &TestC{}, "page").Name("tenant")
+		r.HandleFunc(fmt.Sprintf("/tenants/t%d", i%5), HandlerForTest)
+		r.Remove(prefix)
+	}
+	close(stop)
+	wg.Wait()
+}
+
+func TestChangeRegisteredRoute(t *testing.T) {
+	r := NewRouter()
+	rt := r.HandleFunc("/m", HandlerForTest).Name("m")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			r.ServeHTTP(newRecorder(), newRequest("GET", "http://localhost/m", ""))
+			r.Routes()
+		}
+	}()
+	rt.Methods("GET")
+	rt.Use(func(c Controller) bool { return true })
+	rt.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		w.Write([]byte("changed"))
+	})
+	<-done
+
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("POST", "http://localhost/m", ""))
+	assertEqual(t, http.StatusMethodNotAllowed, rec.Code)
+	rec = newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/m", ""))
+	assertEqual(t, "changed", rec.Body.String())
+
+	routes := r.Routes()
+	assertEqual(t, 1, len(routes))
+	assertEqual(t, "m", routes[0].Name)
+	assertEqual(t, 1, routes[0].Middleware)
+	assertEqual(t, nil, r.Validate())
+
+	r.HandleFunc("/d", HandlerForTest, "GET")
+	r.HandleFunc("/d", HandlerForTest, "POST").Methods("GET")
+	assertNotEqual(t, nil, r.Validate())
+	assertEqual(t, 2, len(r.Routes()))
+}
@@ -0,0 +1,173 @@
+package rapi
+
+import "strings"
+
+// node is a node of compressed radix tree used for routes lookup.
+// Static path fragments are stored in children indexed by the first byte,
+// named parameters (":name") in param child and catch-all ("*name") in
+// catchAll child. Parameter names are taken from the matched route pattern,
+// so routes may use different names at the same position.
+type node struct {
+	path     string
+	indices  []byte
+	children []*node
+	param    *node
+	catchAll *node
+
+	exact  []*Route // routes matching when path ends on this node
+	prefix []*Route // routes matching any path beneath this node
+}
+
+// insert adds route into the tree under the pattern
+func (n *node) insert(pattern string, rt *Route, exact bool) {
+	for {
+		if pattern == "" {
+			if exact {
+				n.exact = append(n.exact, rt)
+			} else {
+				n.prefix = append(n.prefix, rt)
+			}
+			return
+		}
+
+		switch {
+		case pattern[0] == ':' && n.segmentStart():
+			end := strings.IndexByte(pattern, '/')
+			if end < 0 {
+				end = len(pattern)
+			}
+			if n.param == nil {
+				n.param = &node{path: ":"}
+			}
+			n, pattern = n.param, pattern[end:]
+			continue
+		case pattern[0] == '*' && n.segmentStart():
+			if n.catchAll == nil {
+				n.catchAll = &node{path: "*"}
+			}
+			n, pattern = n.catchAll, ""
+			continue
+		}
+
+		s := staticPrefix(pattern)
+		c := n.child(s[0])
+		if c == nil {
+			c = &node{path: s}
+			n.indices = append(n.indices, s[0])
+			n.children = append(n.children, c)
+			n, pattern = c, pattern[len(s):]
+			continue
+		}
+
+		l := commonPrefix(c.path, s)
+		if l < len(c.path) {
+			c.split(l)
+		}
+		n, pattern = c, pattern[l:]
+	}
+}
+
+// split moves the tail of node path starting at i into a new child node
+func (n *node) split(i int) {
+	c := &node{
+		path:     n.path[i:],
+		indices:  n.indices,
+		children: n.children,
+		param:    n.param,
+		catchAll: n.catchAll,
+		exact:    n.exact,
+		prefix:   n.prefix,
+	}
+	*n = node{path: n.path[:i], indices: []byte{c.path[0]}, children: []*node{c}}
+}
+
+func (n *node) child(b byte) *node {
+	for i, v := range n.indices {
+		if v == b {
+			return n.children[i]
+		}
+	}
+	return nil
+}
+
+// lookup searches routes for the path remaining after this node.
+// Static children take precedence over parameters, parameters over catch-all,
+// and the deepest prefix routes are used when no exact route matches.
+// Captured parameter values are appended to vals.
+func (n *node) lookup(path string, vals []string) ([]*Route, []string) {
+	if path == "" && n.exact != nil {
+		return n.exact, vals
+	}
+
+	if path != "" {
+		if c := n.child(path[0]); c != nil && strings.HasPrefix(path, c.path) {
+			if rt, v := c.lookup(path[len(c.path):], vals); rt != nil {
+				return rt, v
+			}
+		}
+	}
+
+	if n.param != nil && n.segmentStart() {
+		end := strings.IndexByte(path, '/')
+		if end < 0 {
+			end = len(path)
+		}
+		if end > 0 {
+			if rt, v := n.param.lookup(path[end:], append(vals, path[:end])); rt != nil {
+				return rt, v
+			}
+		}
+	}
+
+	if n.catchAll != nil && n.segmentStart() {
+		rt := n.catchAll.exact
+		if len(rt) == 0 {
+			rt = n.catchAll.prefix
+		}
+		return rt, append(vals, path)
+	}
+
+	if n.prefix != nil {
+		if path == "" || path[0] == '/' || strings.HasSuffix(n.prefix[0].prefix, "/") {
+			return n.prefix, vals
+		}
+	}
+
+	return nil, vals
+}
+
+// segmentStart reports whether path remaining after the node
+// starts on the path segment boundary
+func (n *node) segmentStart() bool {
+	return n.path == "" || n.path[len(n.path)-1] == '/'
+}
+
+// staticPrefix returns leading static part of the pattern
+// up to the first parameter or catch-all segment
+func staticPrefix(pattern string) string {
+	for i := 1; i < len(pattern); i++ {
+		if pattern[i-1] == '/' && (pattern[i] == ':' || pattern[i] == '*') {
+			return pattern[:i]
+		}
+	}
+	return pattern
+}
+
+func commonPrefix(a, b string) int {
+	i := 0
+	for i < len(a) && i < len(b) && a[i] == b[i] {
+		i++
+	}
+	return i
+}
+
+// paramNames returns names of parameters used in the pattern in order
+func paramNames(pattern string) []string {
+	var names []string
+	for _, v := range strings.Split(pattern, "/") {
+		if len(v) > 1 && (v[0] == ':' || v[0] == '*') {
+			names = append(names, v[1:])
+		}
+	}
+	return names
+}
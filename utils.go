@@ -7,6 +7,7 @@ import (
 	"log"
 	"net/http"
 	"path"
+	"strings"
 	"unicode"
 )
 
@@ -30,6 +31,10 @@ func RenderJSONError(w http.ResponseWriter, code int, s string) {
 
 // RenderJSON common function to render JSON to client
 func RenderJSON(w http.ResponseWriter, code int, s JSONData) {
+	writeJSON(w, code, s)
+}
+
+func writeJSON(w http.ResponseWriter, code int, s interface{}) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.WriteHeader(code)
 	if err := json.NewEncoder(w).Encode(s); err != nil {
@@ -39,6 +44,10 @@ func RenderJSON(w http.ResponseWriter, code int, s JSONData) {
 
 // RenderJSONgzip common function to render gzipped JSON to client
 func RenderJSONgzip(w http.ResponseWriter, code int, s JSONData) {
+	writeJSONgzip(w, code, s)
+}
+
+func writeJSONgzip(w http.ResponseWriter, code int, s interface{}) {
 	w.Header().Set("Content-Type", "application/json; charset=utf-8")
 	w.Header().Set("Content-Encoding", "gzip")
 	w.WriteHeader(code)
@@ -49,6 +58,36 @@ func RenderJSONgzip(w http.ResponseWriter, code int, s JSONData) {
 	}
 }
 
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// lastSegment returns the last non empty segment of the path
+func lastSegment(p string) string {
+	p = strings.TrimSuffix(p, "/")
+	return p[strings.LastIndexByte(p, '/')+1:]
+}
+
+// singularize returns singular form of english noun in plural form
+func singularize(s string) string {
+	switch {
+	case strings.HasSuffix(s, "ies"):
+		return s[:len(s)-3] + "y"
+	case strings.HasSuffix(s, "sses"), strings.HasSuffix(s, "xes"), strings.HasSuffix(s, "ches"), strings.HasSuffix(s, "shes"):
+		return s[:len(s)-2]
+	case strings.HasSuffix(s, "ss"), strings.HasSuffix(s, "us"):
+		return s
+	case strings.HasSuffix(s, "s"):
+		return s[:len(s)-1]
+	}
+	return s
+}
+
 // cleanPath returns the canonical path for p, eliminating . and .. elements.
 // Borrowed from the net/http package.
 func cleanPath(p string) string {
@@ -66,3 +105,42 @@ func cleanPath(p string) string {
 	}
 	return np
 }
+
+// matchPattern matches path against pattern segment by segment.
+// Pattern segments starting with ':' capture the corresponding path segment
+// under the given name. It returns captured parameters and the rest of the path
+// that follows the matched prefix. Pattern is matched only on segment boundary,
+// so "/pages" matches "/pages" and "/pages/1" but not "/pagesx".
+func matchPattern(pattern, path string) (map[string]string, string, bool) {
+	var params map[string]string
+	dir := strings.HasSuffix(pattern, "/")
+	for pattern != "" {
+		if pattern[0] == ':' {
+			end := strings.IndexByte(pattern, '/')
+			if end < 0 {
+				end = len(pattern)
+			}
+			vend := strings.IndexByte(path, '/')
+			if vend < 0 {
+				vend = len(path)
+			}
+			if vend == 0 {
+				return nil, "", false
+			}
+			if params == nil {
+				params = make(map[string]string)
+			}
+			params[pattern[1:end]] = path[:vend]
+			pattern, path = pattern[end:], path[vend:]
+			continue
+		}
+		if path == "" || path[0] != pattern[0] {
+			return nil, "", false
+		}
+		pattern, path = pattern[1:], path[1:]
+	}
+	if !dir && path != "" && path[0] != '/' {
+		return nil, "", false
+	}
+	return params, path, true
+}
@@ -0,0 +1,120 @@
+package rapi
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Validate returns errors found in registered routes
+// including routes of host sub-routers and API versions:
+//  - duplicate routes replacing previously registered ones
+//  - routes shadowed by other routes and never reached
+//  - controllers without actions
+//  - custom actions colliding with standard Index, Show, Create, Update, Destroy
+//
+// Validate is called on every registration if Router.Strict is set,
+// making registration panic on the first error.
+func (r *Router) Validate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.validate()
+}
+
+func (r *Router) validate() error {
+	t := r.load()
+	errs := append([]error{}, r.errs...)
+	for _, rt := range t.routes {
+		errs = append(errs, r.validateRoute(rt)...)
+		if rt.versions == nil {
+			continue
+		}
+		for _, ver := range rt.versions.list() {
+			if err := ver.router.Validate(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	for _, h := range t.hosts {
+		if err := h.router.Validate(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// reportError records registration error
+func (r *Router) reportError(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.addError(err)
+}
+
+// addError records registration error, r.mu must be held
+func (r *Router) addError(err error) {
+	r.errs = append(r.errs, err)
+	if r.Strict {
+		panic(err)
+	}
+}
+
+func (r *Router) validateRoute(rt *Route) []error {
+	var errs []error
+
+	if other := r.shadowing(rt); other != nil {
+		errs = append(errs, fmt.Errorf("rapi: route %s is shadowed by %s", rt.prefix, other.prefix))
+	}
+
+	ct := rt.ctrl
+	if ct == nil {
+		return errs
+	}
+
+	if len(ct.actions) == 0 {
+		errs = append(errs, fmt.Errorf("rapi: controller *%s at %s has no actions", ct.typ, rt.prefix))
+	}
+	for _, v := range ct.extras {
+		for _, m := range meths {
+			a := strings.TrimPrefix(v, m)
+			if a != v && containsString(stdActions, a) {
+				errs = append(errs, fmt.Errorf("rapi: custom action %s of controller *%s collides with %s", v, ct.typ, a))
+			}
+		}
+	}
+	return errs
+}
+
+// shadowing returns route served instead of rt for the path built
+// from rt pattern or nil if rt is reachable
+func (r *Router) shadowing(rt *Route) *Route {
+	rts, _ := r.lookup(samplePath(rt.prefix))
+	if len(rts) == 0 {
+		return nil
+	}
+
+	methods := rt.methods
+	if len(methods) == 0 {
+		methods = []string{""}
+	}
+	for _, m := range methods {
+		if v := routeFor(rts, m); v != rt {
+			if v == nil {
+				v = rts[0]
+			}
+			return v
+		}
+	}
+	return nil
+}
+
+// samplePath returns path matching the pattern
+// with parameters replaced by placeholder
+func samplePath(pattern string) string {
+	segs := strings.Split(pattern, "/")
+	for i, v := range segs {
+		if len(v) > 1 && (v[0] == ':' || v[0] == '*') {
+			segs[i] = "~"
+		}
+	}
+	return strings.Join(segs, "/")
+}
@@ -0,0 +1,58 @@
+package rapi
+
+import (
+	"strings"
+	"testing"
+)
+
+type EmptyC struct {
+	Request
+}
+
+type CollideC struct {
+	Request
+}
+
+func (c *CollideC) Index() {}
+
+func (c *CollideC) GETShow() {}
+
+func TestValidate(t *testing.T) {
+	r := NewRouter()
+	r.Route("/pages", &TestC{}, "page")
+	r.HandleFunc("/users/:id", HandlerForTest, "GET")
+	r.HandleFunc("/users/:user_id", HandlerForTest, "POST")
+	assertEqual(t, nil, r.Validate())
+
+	r.Route("/pages", &TestC{}, "page")
+	r.HandleFunc("/users/:uid", HandlerForTest, "GET")
+	r.HandlePrefix("/files/:a", nil)
+	r.HandlePrefix("/files/:b", nil)
+	r.Route("/empty", &EmptyC{}, "empty")
+	r.Route("/collide", &CollideC{}, "collide")
+
+	err := r.Validate()
+	assertNotEqual(t, nil, err)
+	msg := err.Error()
+	for _, v := range []string{
+		"duplicate route /pages",
+		"route /users/:uid is shadowed by /users/:id",
+		"route /files/:b is shadowed by /files/:a",
+		"controller *rapi.EmptyC at /empty has no actions",
+		"custom action GETShow of controller *rapi.CollideC collides with Show",
+	} {
+		assertEqual(t, true, strings.Contains(msg, v))
+	}
+}
+
+func TestStrict(t *testing.T) {
+	r := NewRouter()
+	r.Strict = true
+	r.Route("/pages", &TestC{}, "page")
+
+	defer func() {
+		err := recover()
+		assertNotEqual(t, nil, err)
+	}()
+	r.Route("/empty", &EmptyC{}, "empty")
+}
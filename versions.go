@@ -0,0 +1,208 @@
+package rapi
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Versions is a group of API versions served beneath the same prefix.
+// Version is selected by path segment (/api/v2/pages), by Accept header
+// media type (application/vnd.vendor.v2+json or version parameter)
+// or by custom header. Request without version is served by the latest one.
+// Route missing in requested version is served by the latest version
+// lower than requested having it.
+// Routes of versions are listed by Router.Routes with /vN prefix,
+// validated by Router.Validate and named for Router.URL of the parent router.
+type Versions struct {
+	vendor string
+	header string
+	router *Router // parent router
+
+	mu       sync.RWMutex
+	versions []*apiVersion // sorted by number descending
+}
+
+type apiVersion struct {
+	number int
+	router *Router
+	sunset time.Time
+	depr   bool
+}
+
+// Versions registers API versions group beneath the route prefix.
+// Vendor is the media type vendor name and header is the custom header name
+// used to request version, any of them can be empty.
+// ex:
+//    v := r.PathPrefix("/api").Versions("acme", "X-API-Version")
+//    v.Version(1).Route("/pages", &PagesV1{}, "page")
+//    v.Version(2).Route("/pages", &PagesV2{}, "page")
+//    v.Version(2).Route("/users", &Users{}, "user")
+//    v.Deprecate(1, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+//
+//    // GET /api/v1/pages                         served by PagesV1
+//    // GET /api/pages                            served by PagesV2
+//    // GET /api/pages, X-API-Version: 1          served by PagesV1
+//    // GET /api/users, Accept: application/vnd.acme.v1+json served by Users
+func (r *Route) Versions(vendor, header string) *Versions {
+	v := &Versions{vendor: vendor, header: header, router: r.router}
+	rt := r.mountRoute("", v)
+	rt.versions = v
+	rt.addRoute(false)
+	return v
+}
+
+// Version returns route for registering routes of the API version.
+// Version router is created with CleanPath, TrailingSlash and Strict
+// settings of the router, like Router.Host.
+func (v *Versions) Version(n int) *Route {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, ver := range v.versions {
+		if ver.number == n {
+			return ver.router.NewRoute("")
+		}
+	}
+
+	ver := &apiVersion{number: n, router: v.router.subRouter()}
+	v.versions = append(v.versions, ver)
+	sort.Slice(v.versions, func(i, j int) bool {
+		return v.versions[i].number > v.versions[j].number
+	})
+	return ver.router.NewRoute("")
+}
+
+// list returns registered versions sorted by number descending
+func (v *Versions) list() []*apiVersion {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+	return append([]*apiVersion{}, v.versions...)
+}
+
+// Deprecate marks API version deprecated. Responses of deprecated version
+// have Deprecation header and Sunset header if sunset time is not zero.
+func (v *Versions) Deprecate(n int, sunset time.Time) {
+	v.Version(n)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	for _, ver := range v.versions {
+		if ver.number == n {
+			ver.depr, ver.sunset = true, sunset
+		}
+	}
+}
+
+func (v *Versions) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	n, fromPath := pathVersion(req.URL.Path)
+	if !fromPath {
+		n = v.requested(req)
+	}
+
+	v.mu.RLock()
+	var ver *apiVersion
+	var depr bool
+	var sunset time.Time
+	for _, item := range v.versions {
+		if n > 0 && item.number > n {
+			continue
+		}
+		p := req.URL.Path
+		if fromPath {
+			_, p, _ = matchPattern("/v"+strconv.Itoa(n), p)
+		}
+		if item.router.has(cleanPath(p)) {
+			ver, depr, sunset = item, item.depr, item.sunset
+			break
+		}
+	}
+	v.mu.RUnlock()
+
+	if ver == nil {
+		notFound(w, req)
+		return
+	}
+
+	if depr {
+		w.Header().Set("Deprecation", "true")
+		if !sunset.IsZero() {
+			w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+		}
+	}
+
+	if fromPath {
+		mountHandler("/v"+strconv.Itoa(n), ver.router).ServeHTTP(w, req)
+		return
+	}
+	ver.router.ServeHTTP(w, req)
+}
+
+// requested returns version requested by Accept or custom header, 0 if none
+func (v *Versions) requested(req *http.Request) int {
+	if v.vendor != "" {
+		for _, mt := range strings.Split(req.Header.Get("Accept"), ",") {
+			if n := mediaTypeVersion(mt, v.vendor); n > 0 {
+				return n
+			}
+		}
+	}
+	if v.header != "" {
+		s := strings.TrimPrefix(strings.TrimSpace(req.Header.Get(v.header)), "v")
+		if n, err := strconv.Atoi(s); err == nil && n > 0 {
+			return n
+		}
+	}
+	return 0
+}
+
+// pathVersion returns version from the first path segment like /v2
+func pathVersion(p string) (int, bool) {
+	seg := strings.TrimPrefix(p, "/")
+	if i := strings.IndexByte(seg, '/'); i >= 0 {
+		seg = seg[:i]
+	}
+	if len(seg) < 2 || seg[0] != 'v' {
+		return 0, false
+	}
+	n, err := strconv.Atoi(seg[1:])
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	return n, true
+}
+
+// mediaTypeVersion returns version from media type like
+// application/vnd.vendor.v2+json or application/vnd.vendor+json; version=2
+func mediaTypeVersion(mt, vendor string) int {
+	parts := strings.Split(mt, ";")
+	t := strings.ToLower(strings.TrimSpace(parts[0]))
+	prefix := "application/vnd." + strings.ToLower(vendor)
+	if !strings.HasPrefix(t, prefix) {
+		return 0
+	}
+	t = t[len(prefix):]
+	if t != "" && t[0] != '.' && t[0] != '+' {
+		return 0
+	}
+
+	t = strings.TrimSuffix(t, "+json")
+	if strings.HasPrefix(t, ".v") {
+		if n, err := strconv.Atoi(t[2:]); err == nil {
+			return n
+		}
+	}
+
+	for _, p := range parts[1:] {
+		kv := strings.SplitN(strings.TrimSpace(p), "=", 2)
+		if len(kv) == 2 && strings.ToLower(kv[0]) == "version" {
+			if n, err := strconv.Atoi(strings.TrimPrefix(kv[1], "v")); err == nil {
+				return n
+			}
+		}
+	}
+	return 0
+}
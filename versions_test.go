@@ -0,0 +1,147 @@
+package rapi
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+type PagesV1 struct {
+	Request
+}
+
+func (c *PagesV1) Index() {
+	c.RenderJSON(200, JSONData{"version": 1})
+}
+
+type PagesV2 struct {
+	Request
+}
+
+func (c *PagesV2) Index() {
+	c.RenderJSON(200, JSONData{"version": 2})
+}
+
+func TestVersions(t *testing.T) {
+	r := NewRouter()
+	v := r.PathPrefix("/api").Versions("acme", "X-API-Version")
+	v.Version(1).Route("/pages", &PagesV1{}, "page")
+	v.Version(1).Route("/users", &PagesV1{}, "user")
+	v.Version(2).Route("/pages", &PagesV2{}, "page")
+	v.Deprecate(1, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+
+	get := func(path string, header ...string) *http.Response {
+		req := newRequest("GET", "http://localhost"+path, "")
+		for i := 0; i < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		rec := newRecorder()
+		r.ServeHTTP(rec, req)
+		res := rec.Result()
+		res.Header.Set("X-Body", rec.Body.String())
+		return res
+	}
+
+	res := get("/api/pages")
+	assertEqual(t, "{\"version\":2}\n", res.Header.Get("X-Body"))
+	assertEqual(t, "", res.Header.Get("Deprecation"))
+
+	res = get("/api/v1/pages")
+	assertEqual(t, "{\"version\":1}\n", res.Header.Get("X-Body"))
+	assertEqual(t, "true", res.Header.Get("Deprecation"))
+	assertEqual(t, "Fri, 01 Jan 2027 00:00:00 GMT", res.Header.Get("Sunset"))
+
+	res = get("/api/pages", "X-API-Version", "1")
+	assertEqual(t, "{\"version\":1}\n", res.Header.Get("X-Body"))
+
+	res = get("/api/pages", "Accept", "application/vnd.acme.v1+json")
+	assertEqual(t, "{\"version\":1}\n", res.Header.Get("X-Body"))
+
+	res = get("/api/pages", "Accept", "application/vnd.acme+json; version=1")
+	assertEqual(t, "{\"version\":1}\n", res.Header.Get("X-Body"))
+
+	res = get("/api/v3/pages")
+	assertEqual(t, "{\"version\":2}\n", res.Header.Get("X-Body"))
+
+	res = get("/api/v2/users")
+	assertEqual(t, "{\"version\":1}\n", res.Header.Get("X-Body"))
+	assertEqual(t, "true", res.Header.Get("Deprecation"))
+
+	res = get("/api/v2/unknown")
+	assertEqual(t, http.StatusNotFound, res.StatusCode)
+}
+
+func TestMediaTypeVersion(t *testing.T) {
+	assertEqual(t, 2, mediaTypeVersion("application/vnd.acme.v2+json", "acme"))
+	assertEqual(t, 3, mediaTypeVersion(" application/vnd.acme+json;version=3", "acme"))
+	assertEqual(t, 0, mediaTypeVersion("application/json", "acme"))
+	assertEqual(t, 0, mediaTypeVersion("application/vnd.other.v2+json", "acme"))
+	assertEqual(t, 0, mediaTypeVersion("application/vnd.acmecorp+json; version=2", "acme"))
+	assertEqual(t, 0, mediaTypeVersion("application/vnd.acmecorp.v2+json", "acme"))
+	assertEqual(t, 4, mediaTypeVersion("application/vnd.acme; version=4", "acme"))
+}
+
+func TestVersionsRoutes(t *testing.T) {
+	r := NewRouter()
+	r.NotFound = http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		http.Error(w, "custom not found", http.StatusNotFound)
+	})
+	v := r.PathPrefix("/tenants/:tenant/api").Versions("acme", "")
+	v.Version(1).Route("/pages", &PagesV1{}, "page").Name("pages")
+	v.Version(1).Route("/users", &PagesV1{}, "user").Name("users")
+	v.Version(2).Route("/pages", &PagesV2{}, "page").Name("pages")
+	v.Version(2).Route("/pages", &TestC{}, "page")
+	r.Mount("/legacy", NewRouter())
+
+	routes := r.Routes()
+	assertEqual(t, 5, len(routes))
+	assertEqual(t, "/tenants/:tenant/api/v1/pages", routes[2].Prefix)
+	assertEqual(t, "pages", routes[2].Name)
+	assertEqual(t, "*rapi.TestC", routes[4].Controller)
+
+	u, err := r.URL("pages", "tenant", "acme", "id", "5")
+	assertEqual(t, nil, err)
+	assertEqual(t, "/tenants/acme/api/v2/pages/5", u)
+	u, _ = r.URL("users", "tenant", "acme")
+	assertEqual(t, "/tenants/acme/api/v1/users", u)
+	_, err = r.URL("users")
+	assertNotEqual(t, nil, err)
+
+	err = r.Validate()
+	assertNotEqual(t, nil, err)
+	assertEqual(t, "rapi: duplicate route /pages replaces previously registered one", err.Error())
+
+	for _, path := range []string{"/tenants/acme/api/v2/unknown", "/legacy/unknown"} {
+		rec := newRecorder()
+		r.ServeHTTP(rec, newRequest("GET", "http://localhost"+path, ""))
+		assertEqual(t, http.StatusNotFound, rec.Code)
+		assertEqual(t, "custom not found\n", rec.Body.String())
+	}
+
+	r = NewRouter()
+	r.Strict = true
+	v = r.PathPrefix("/api").Versions("acme", "")
+	v.Version(1).Route("/pages", &PagesV1{}, "page")
+	defer func() { assertNotEqual(t, nil, recover()) }()
+	v.Version(1).Route("/pages", &PagesV2{}, "page")
+}
+
+func TestVersionsConcurrentDeprecate(t *testing.T) {
+	r := NewRouter()
+	v := r.PathPrefix("/api").Versions("acme", "")
+	v.Version(1).Route("/pages", &PagesV1{}, "page")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 50; i++ {
+			r.ServeHTTP(newRecorder(), newRequest("GET", "http://localhost/api/v1/pages", ""))
+		}
+	}()
+	v.Deprecate(1, time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC))
+	<-done
+
+	rec := newRecorder()
+	r.ServeHTTP(rec, newRequest("GET", "http://localhost/api/v1/pages", ""))
+	assertEqual(t, "true", rec.Header().Get("Deprecation"))
+}